@@ -0,0 +1,31 @@
+package account
+
+import "time"
+
+const (
+	KindExport   = "export"
+	KindDeletion = "deletion"
+
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+// Job is a data export or an account deletion running in the background
+type Job struct {
+	ID          string
+	UserID      string
+	Kind        string
+	Status      string
+	Attempts    int
+	ArchivePath string
+	Error       string
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (j Job) IsDone() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed
+}
@@ -0,0 +1,113 @@
+package account
+
+import (
+	"funding-app/app/campaign"
+	"funding-app/app/imaging"
+	"funding-app/app/user"
+	"time"
+)
+
+type JobFormatter struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func FormatJob(job Job) JobFormatter {
+	formatter := JobFormatter{}
+	formatter.ID = job.ID
+	formatter.Kind = job.Kind
+	formatter.Status = job.Status
+	formatter.Error = job.Error
+	formatter.ExpiresAt = job.ExpiresAt
+	formatter.CreatedAt = job.CreatedAt
+	formatter.UpdatedAt = job.UpdatedAt
+
+	return formatter
+}
+
+// ExportCampaignFormatter carries the full campaign, the public formatter leaves out what only the owner wrote
+type ExportCampaignFormatter struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Slug             string    `json:"slug"`
+	ShortDescription string    `json:"short_description"`
+	Description      string    `json:"description"`
+	Perks            string    `json:"perks"`
+	GoalAmount       int       `json:"goal_amount"`
+	CurrentAmount    int       `json:"current_amount"`
+	BackerCount      int       `json:"backer_count"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type ExportImageFormatter struct {
+	Kind       string           `json:"kind"`
+	CampaignID string           `json:"campaign_id,omitempty"`
+	URL        string           `json:"url"`
+	URLs       imaging.Variants `json:"urls"`
+	IsPrimary  bool             `json:"is_primary"`
+	CreatedAt  *time.Time       `json:"created_at,omitempty"`
+}
+
+func FormatExportCampaigns(campaigns []campaign.Campaign) []ExportCampaignFormatter {
+	formatter := []ExportCampaignFormatter{}
+
+	for _, ownedCampaign := range campaigns {
+		campaignFormatter := ExportCampaignFormatter{}
+		campaignFormatter.ID = ownedCampaign.ID
+		campaignFormatter.Name = ownedCampaign.Name
+		campaignFormatter.Slug = ownedCampaign.Slug
+		campaignFormatter.ShortDescription = ownedCampaign.ShortDescription
+		campaignFormatter.Description = ownedCampaign.Description
+		campaignFormatter.Perks = ownedCampaign.Perks
+		campaignFormatter.GoalAmount = ownedCampaign.GoalAmount
+		campaignFormatter.CurrentAmount = ownedCampaign.CurrentAmount
+		campaignFormatter.BackerCount = ownedCampaign.BackerCount
+		campaignFormatter.Status = ownedCampaign.Status
+		campaignFormatter.CreatedAt = ownedCampaign.CreatedAt
+		campaignFormatter.UpdatedAt = ownedCampaign.UpdatedAt
+
+		formatter = append(formatter, campaignFormatter)
+	}
+
+	return formatter
+}
+
+// FormatExportImages lists the avatar and every campaign image the user uploaded
+func FormatExportImages(exportedUser user.User, campaigns []campaign.Campaign) []ExportImageFormatter {
+	formatter := []ExportImageFormatter{}
+
+	if exportedUser.AvatarFileName != "" {
+		imageFormatter := ExportImageFormatter{}
+		imageFormatter.Kind = "avatar"
+		imageFormatter.URL = exportedUser.AvatarFileName
+		imageFormatter.URLs = exportedUser.AvatarVariants
+		imageFormatter.IsPrimary = true
+
+		formatter = append(formatter, imageFormatter)
+	}
+
+	for _, ownedCampaign := range campaigns {
+		for _, image := range ownedCampaign.CampaignImages {
+			createdAt := image.CreatedAt
+
+			imageFormatter := ExportImageFormatter{}
+			imageFormatter.Kind = "campaign"
+			imageFormatter.CampaignID = image.CampaignID
+			imageFormatter.URL = image.FileName
+			imageFormatter.URLs = image.Variants
+			imageFormatter.IsPrimary = image.IsPrimary == 1
+			imageFormatter.CreatedAt = &createdAt
+
+			formatter = append(formatter, imageFormatter)
+		}
+	}
+
+	return formatter
+}
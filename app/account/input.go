@@ -0,0 +1,10 @@
+package account
+
+type (
+	// DeleteAccountInput takes the password, accounts signed up through a provider have none
+	// and use the token from the confirmation email instead
+	DeleteAccountInput struct {
+		Password string `json:"password" validate:"required_without=Token"`
+		Token    string `json:"token" validate:"required_without=Password"`
+	}
+)
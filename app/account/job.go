@@ -0,0 +1,196 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"funding-app/app/auth"
+	"funding-app/app/mailer"
+	"funding-app/app/transaction"
+	"funding-app/app/user"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const exportPageSize = 100
+
+// export writes the user's data into a zip of JSON files and mails them a download link
+func (s *service) export(ctx context.Context, job *Job) error {
+	exportedUser, err := s.userService.GetUserByID(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	campaigns, err := s.campaignService.GetOwnedCampaigns(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	backings, err := s.backings(job.UserID)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := s.authService.GetSessionHistory(job.UserID)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user.FormatProfile(exportedUser)},
+		{"campaigns.json", FormatExportCampaigns(campaigns)},
+		{"images.json", FormatExportImages(exportedUser, campaigns)},
+		{"backings.json", transaction.FormatTransactions(backings)},
+		{"sessions.json", auth.FormatSessions(sessions, "")},
+	}
+
+	archivePath := filepath.Join(s.config.ExportDir, job.ID+".zip")
+
+	archive, err := os.OpenFile(archivePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	zipWriter := zip.NewWriter(archive)
+
+	for _, file := range files {
+		err = writeJSON(zipWriter, file.name, file.data)
+		if err != nil {
+			break
+		}
+	}
+
+	if closeErr := zipWriter.Close(); err == nil {
+		err = closeErr
+	}
+
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(archivePath)
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.config.ExportTTL)
+	job.ArchivePath = archivePath
+	job.ExpiresAt = &expiresAt
+
+	s.mailer.SendAsync(mailer.Mail{
+		To:       exportedUser.Email,
+		Template: mailer.TemplateDataExportReady,
+		Data: map[string]interface{}{
+			"Name":      exportedUser.Name,
+			"URL":       s.config.ExportURL + "?id=" + url.QueryEscape(job.ID),
+			"ExpiresIn": s.config.ExportTTL.String(),
+		},
+	})
+
+	return nil
+}
+
+// delete anonymizes the user, purging the credentials first means a retry still knows the original email
+func (s *service) delete(ctx context.Context, job *Job) error {
+	err := s.checkLiveCampaigns(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	deletedUser, err := s.userService.GetUserByID(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = s.accountRepository.PurgePersonalData(ctx, deletedUser.ID, deletedUser.Email)
+	if err != nil {
+		return err
+	}
+
+	err = s.removeArchives(ctx, deletedUser.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.userService.AnonymizeUser(ctx, deletedUser.ID)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("user_id", deletedUser.ID).Info("account deleted")
+
+	s.mailer.SendAsync(mailer.Mail{
+		To:       deletedUser.Email,
+		Template: mailer.TemplateAccountDeleted,
+		Data: map[string]interface{}{
+			"Name": deletedUser.Name,
+		},
+	})
+
+	return nil
+}
+
+// backings pages through every transaction of the user
+func (s *service) backings(userID string) ([]transaction.Transaction, error) {
+	backings := []transaction.Transaction{}
+
+	for page := 1; ; page++ {
+		transactions, total, err := s.transactionService.GetUserTransactions(transaction.GetUserTransactionsInput{
+			UserID:  userID,
+			Page:    page,
+			PerPage: exportPageSize,
+		})
+		if err != nil {
+			return backings, err
+		}
+
+		backings = append(backings, transactions...)
+
+		if len(transactions) == 0 || len(backings) >= total {
+			return backings, nil
+		}
+	}
+}
+
+// removeArchives drops earlier exports, they hold the data the deletion is getting rid of
+func (s *service) removeArchives(ctx context.Context, userID string) error {
+	jobs, err := s.accountRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.ArchivePath == "" {
+			continue
+		}
+
+		err := os.Remove(job.ArchivePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		job.ArchivePath = ""
+		s.save(ctx, job)
+	}
+
+	return nil
+}
+
+func writeJSON(zipWriter *zip.Writer, name string, data interface{}) error {
+	writer, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(data)
+}
@@ -0,0 +1,285 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	Save(ctx context.Context, job Job) (Job, error)
+	FindByID(ctx context.Context, ID string) (Job, error)
+	FindByUserID(ctx context.Context, userID string) ([]Job, error)
+	FindUnfinished(ctx context.Context) ([]Job, error)
+	FindUnfinishedByUserID(ctx context.Context, userID string, kind string) (Job, error)
+	FindExpiredExports(ctx context.Context) ([]Job, error)
+	Update(ctx context.Context, job Job) (Job, error)
+	PurgePersonalData(ctx context.Context, userID string, email string) error
+	SaveDeletionToken(ctx context.Context, ID string, userID string, tokenHash string, expiresAt time.Time) error
+	ConsumeDeletionToken(ctx context.Context, userID string, tokenHash string) (bool, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+const selectJob = "SELECT id, user_id, kind, status, attempts, archive_path, error, expires_at, created_at, updated_at FROM account_jobs"
+
+func NewAccountRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, job Job) (Job, error) {
+	sqlQuery := "INSERT INTO account_jobs (id, user_id, kind, status, attempts, archive_path, error, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $8)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return job, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		job.ID,
+		job.UserID,
+		job.Kind,
+		job.Status,
+		job.Attempts,
+		job.ArchivePath,
+		job.Error,
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return job, err
+	}
+
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	return job, nil
+}
+
+func (r *repository) FindByID(ctx context.Context, ID string) (Job, error) {
+	jobs, err := r.query(ctx, selectJob+" WHERE id = $1", ID)
+	if err != nil || len(jobs) == 0 {
+		return Job{}, err
+	}
+
+	return jobs[0], nil
+}
+
+func (r *repository) FindByUserID(ctx context.Context, userID string) ([]Job, error) {
+	return r.query(ctx, selectJob+" WHERE user_id = $1 ORDER BY created_at", userID)
+}
+
+func (r *repository) FindUnfinished(ctx context.Context) ([]Job, error) {
+	return r.query(ctx, selectJob+" WHERE status IN ('pending', 'processing') ORDER BY created_at")
+}
+
+func (r *repository) FindUnfinishedByUserID(ctx context.Context, userID string, kind string) (Job, error) {
+	jobs, err := r.query(ctx, selectJob+" WHERE user_id = $1 AND kind = $2 AND status IN ('pending', 'processing') ORDER BY created_at DESC LIMIT 1", userID, kind)
+	if err != nil || len(jobs) == 0 {
+		return Job{}, err
+	}
+
+	return jobs[0], nil
+}
+
+// FindExpiredExports returns finished exports whose archive is past its expiry and still on disk
+func (r *repository) FindExpiredExports(ctx context.Context) ([]Job, error) {
+	return r.query(ctx, selectJob+" WHERE kind = 'export' AND archive_path <> '' AND expires_at <= $1", time.Now().Format(layoutDateTime))
+}
+
+func (r *repository) Update(ctx context.Context, job Job) (Job, error) {
+	sqlQuery := "UPDATE account_jobs SET status = $1, attempts = $2, archive_path = $3, error = $4, expires_at = $5, updated_at = $6 WHERE id = $7"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return job, err
+	}
+
+	defer stmt.Close()
+
+	var expiresAt interface{}
+	if job.ExpiresAt != nil {
+		expiresAt = job.ExpiresAt.Format(layoutDateTime)
+	}
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		job.Status,
+		job.Attempts,
+		job.ArchivePath,
+		job.Error,
+		expiresAt,
+		now.Format(layoutDateTime),
+		job.ID,
+	)
+	if err != nil {
+		return job, err
+	}
+
+	job.UpdatedAt = now
+	return job, nil
+}
+
+// PurgePersonalData removes everything that identifies the user or lets anyone sign in as them,
+// sessions are revoked instead of deleted so the other instances drop them from their denylist sync
+func (r *repository) PurgePersonalData(ctx context.Context, userID string, email string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	now := time.Now().Format(layoutDateTime)
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE sessions SET revoked_at = COALESCE(revoked_at, $1), device = '', ip_address = '', user_agent = '' WHERE user_id = $2", []interface{}{now, userID}},
+		{"UPDATE refresh_tokens SET revoked_at = COALESCE(revoked_at, $1) WHERE user_id = $2", []interface{}{now, userID}},
+		{"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1), last_used_ip = '' WHERE user_id = $2", []interface{}{now, userID}},
+		{"DELETE FROM recovery_codes WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM two_factor_challenges WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM two_factors WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM user_identities WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM password_resets WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM account_deletion_tokens WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM email_verifications WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM login_failures WHERE email = LOWER($1)", []interface{}{email}},
+		{"UPDATE users SET failed_logins = 0, locked_until = NULL, suspend_reason = '', deleted_at = $1 WHERE id = $2", []interface{}{now, userID}},
+	}
+
+	for _, statement := range statements {
+		_, err = tx.ExecContext(ctx, statement.query, statement.args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SaveDeletionToken replaces the deletion tokens of the user so only the newest link works
+func (r *repository) SaveDeletionToken(ctx context.Context, ID string, userID string, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM account_deletion_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO account_deletion_tokens (id, user_id, token_hash, expires_at, created_at) VALUES($1, $2, $3, $4, $5)",
+		ID,
+		userID,
+		tokenHash,
+		expiresAt.Format(layoutDateTime),
+		time.Now().Format(layoutDateTime),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeDeletionToken deletes the token in the same statement that checks it, so a link works once
+func (r *repository) ConsumeDeletionToken(ctx context.Context, userID string, tokenHash string) (bool, error) {
+	sqlQuery := "DELETE FROM account_deletion_tokens WHERE token_hash = $1 AND user_id = $2 AND expires_at > $3 RETURNING id"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	var ID string
+	err = stmt.QueryRowContext(ctx, tokenHash, userID, time.Now().Format(layoutDateTime)).Scan(&ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *repository) query(ctx context.Context, sqlQuery string, args ...interface{}) ([]Job, error) {
+	jobs := []Job{}
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return jobs, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return jobs, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		job := Job{}
+		var createdAt, updatedAt string
+		var expiresAt sql.NullString
+
+		err := rows.Scan(
+			&job.ID,
+			&job.UserID,
+			&job.Kind,
+			&job.Status,
+			&job.Attempts,
+			&job.ArchivePath,
+			&job.Error,
+			&expiresAt,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return jobs, err
+		}
+
+		if job.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if job.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
+		}
+
+		if expiresAt.Valid {
+			parsed, err := time.Parse(time.RFC3339, expiresAt.String)
+			if err != nil {
+				log.Error(err)
+			} else {
+				job.ExpiresAt = &parsed
+			}
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
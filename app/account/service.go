@@ -0,0 +1,270 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"funding-app/app/auth"
+	"funding-app/app/campaign"
+	"funding-app/app/helper"
+	"funding-app/app/jobqueue"
+	"funding-app/app/mailer"
+	"funding-app/app/transaction"
+	"funding-app/app/user"
+	"net/url"
+	"os"
+	"time"
+)
+
+var (
+	ErrQueueFull       = errors.New("account job queue is full, try again later")
+	ErrJobNotFound     = errors.New("no account job found")
+	ErrArchiveNotReady = errors.New("export archive is not available")
+	ErrLiveCampaigns   = errors.New("close your active campaigns before deleting the account")
+	ErrInvalidToken    = errors.New("deletion link is invalid or has expired")
+)
+
+type Config struct {
+	Workers         int
+	QueueSize       int
+	MaxAttempts     int
+	ExportDir       string
+	ExportTTL       time.Duration
+	ExportURL       string
+	CleanupInterval time.Duration
+	DeletionURL     string
+	DeletionTTL     time.Duration
+}
+
+type Service interface {
+	RequestExport(userID string) (Job, error)
+	RequestDeletionLink(userID string) error
+	RequestDeletion(userID string, input DeleteAccountInput) (Job, error)
+	GetJob(ID string, userID string) (Job, error)
+	OpenArchive(ID string, userID string) (*os.File, error)
+	Start(ctx context.Context)
+}
+
+type service struct {
+	accountRepository  Repository
+	userService        user.Service
+	campaignService    campaign.Service
+	transactionService transaction.Service
+	authService        auth.Service
+	mailer             mailer.Mailer
+	config             Config
+	runner             *jobqueue.Runner
+}
+
+func NewAccountService(accountRepository Repository, userService user.Service, campaignService campaign.Service, transactionService transaction.Service, authService auth.Service, mailer mailer.Mailer, config Config) (Service, error) {
+	err := os.MkdirAll(config.ExportDir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &service{
+		accountRepository:  accountRepository,
+		userService:        userService,
+		campaignService:    campaignService,
+		transactionService: transactionService,
+		authService:        authService,
+		mailer:             mailer,
+		config:             config,
+	}
+
+	s.runner = jobqueue.NewRunner(config.Workers, config.QueueSize, s.process)
+	return s, nil
+}
+
+// RequestExport queues an archive of everything stored about the user, a running export is returned as is
+func (s *service) RequestExport(userID string) (Job, error) {
+	return s.enqueue(userID, KindExport)
+}
+
+// RequestDeletionLink mails a link that confirms the deletion, it proves the user still owns the
+// email when there is no password to ask for
+func (s *service) RequestDeletionLink(userID string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	currentUser, err := s.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	// only the newest link should work
+	err = s.accountRepository.SaveDeletionToken(ctx, helper.GenerateID(), userID, hashToken(token), time.Now().Add(s.config.DeletionTTL))
+	if err != nil {
+		return err
+	}
+
+	s.mailer.SendAsync(mailer.Mail{
+		To:       currentUser.Email,
+		Template: mailer.TemplateAccountDeletionConfirmation,
+		Data: map[string]interface{}{
+			"Name":      currentUser.Name,
+			"URL":       s.config.DeletionURL + "?token=" + url.QueryEscape(token),
+			"ExpiresIn": s.config.DeletionTTL.String(),
+		},
+	})
+
+	return nil
+}
+
+// RequestDeletion reauthenticates the user and queues the anonymization of their account
+func (s *service) RequestDeletion(userID string, input DeleteAccountInput) (Job, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := s.reauthenticate(ctx, userID, input)
+	if err != nil {
+		return Job{}, err
+	}
+
+	err = s.checkLiveCampaigns(ctx, userID)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return s.enqueue(userID, KindDeletion)
+}
+
+func (s *service) GetJob(ID string, userID string) (Job, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	job, err := s.accountRepository.FindByID(ctx, ID)
+	if err != nil {
+		return job, err
+	}
+
+	// someone else's job looks the same as a missing one
+	if job.ID == "" || job.UserID != userID {
+		return Job{}, ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+// OpenArchive returns the finished export archive, the caller closes the file
+func (s *service) OpenArchive(ID string, userID string) (*os.File, error) {
+	job, err := s.GetJob(ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Kind != KindExport || job.Status != StatusCompleted || job.ArchivePath == "" {
+		return nil, ErrArchiveNotReady
+	}
+
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now()) {
+		return nil, ErrArchiveNotReady
+	}
+
+	file, err := os.Open(job.ArchivePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrArchiveNotReady
+		}
+
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (s *service) enqueue(userID string, kind string) (Job, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	job, err := s.accountRepository.FindUnfinishedByUserID(ctx, userID, kind)
+	if err != nil {
+		return job, err
+	}
+
+	if job.ID != "" {
+		return job, nil
+	}
+
+	job = Job{
+		ID:     helper.GenerateID(),
+		UserID: userID,
+		Kind:   kind,
+		Status: StatusPending,
+	}
+
+	job, err = s.accountRepository.Save(ctx, job)
+	if err != nil {
+		return job, err
+	}
+
+	if !s.runner.Enqueue(job.ID) {
+		job.Status = StatusFailed
+		job.Error = ErrQueueFull.Error()
+		s.accountRepository.Update(ctx, job)
+
+		return job, ErrQueueFull
+	}
+
+	return job, nil
+}
+
+// reauthenticate accepts the password or a token from the deletion email, the token is checked
+// first so it is used up even when the password is sent along with it
+func (s *service) reauthenticate(ctx context.Context, userID string, input DeleteAccountInput) error {
+	if input.Token != "" {
+		consumed, err := s.accountRepository.ConsumeDeletionToken(ctx, userID, hashToken(input.Token))
+		if err != nil {
+			return err
+		}
+
+		if !consumed {
+			return ErrInvalidToken
+		}
+
+		return nil
+	}
+
+	_, err := s.userService.VerifyPassword(ctx, userID, input.Password)
+	return err
+}
+
+// checkLiveCampaigns keeps backers from losing the owner of a campaign they are still funding
+func (s *service) checkLiveCampaigns(ctx context.Context, userID string) error {
+	campaigns, err := s.campaignService.GetCampaigns(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, ownedCampaign := range campaigns {
+		if ownedCampaign.Status == campaign.StatusActive {
+			return ErrLiveCampaigns
+		}
+	}
+
+	return nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package account
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Start requeues jobs left over from a previous run, spawns the worker pool and removes expired archives
+func (s *service) Start(ctx context.Context) {
+	unfinished, err := s.accountRepository.FindUnfinished(ctx)
+	if err != nil {
+		log.Error(err)
+	}
+
+	unfinishedIDs := []string{}
+	for _, job := range unfinished {
+		unfinishedIDs = append(unfinishedIDs, job.ID)
+	}
+
+	s.runner.Start(ctx, unfinishedIDs)
+
+	go func() {
+		ticker := time.NewTicker(s.config.CleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.removeExpiredArchives(ctx)
+			}
+		}
+	}()
+}
+
+func (s *service) process(ctx context.Context, ID string) {
+	job, err := s.accountRepository.FindByID(ctx, ID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if job.ID == "" || job.IsDone() {
+		return
+	}
+
+	job.Attempts++
+	job.Status = StatusProcessing
+	job = s.save(ctx, job)
+
+	switch job.Kind {
+	case KindExport:
+		err = s.export(ctx, &job)
+	case KindDeletion:
+		err = s.delete(ctx, &job)
+	}
+
+	if err == nil {
+		job.Status = StatusCompleted
+		job.Error = ""
+		s.save(ctx, job)
+		return
+	}
+
+	log.WithFields(log.Fields{"job_id": job.ID, "kind": job.Kind, "attempt": job.Attempts}).Error(err)
+	job.Error = err.Error()
+
+	// live campaigns won't go away by retrying
+	if job.Attempts >= s.config.MaxAttempts || err == ErrLiveCampaigns {
+		job.Status = StatusFailed
+		s.save(ctx, job)
+		return
+	}
+
+	job.Status = StatusPending
+	s.save(ctx, job)
+
+	s.runner.Retry(ctx, job.ID, job.Attempts)
+}
+
+func (s *service) save(ctx context.Context, job Job) Job {
+	updatedJob, err := s.accountRepository.Update(ctx, job)
+	if err != nil {
+		log.Error(err)
+	}
+
+	return updatedJob
+}
+
+func (s *service) removeExpiredArchives(ctx context.Context) {
+	jobs, err := s.accountRepository.FindExpiredExports(ctx)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	for _, job := range jobs {
+		err := os.Remove(job.ArchivePath)
+		if err != nil && !os.IsNotExist(err) {
+			log.Error(err)
+			continue
+		}
+
+		job.ArchivePath = ""
+		s.save(ctx, job)
+	}
+}
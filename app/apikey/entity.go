@@ -0,0 +1,26 @@
+package apikey
+
+import "time"
+
+// APIKey lets scripts call the API on behalf of a user, only the hash of the key is stored
+type APIKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string
+	UsageCount int64
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// Usage is what happened with a key since the last flush
+type Usage struct {
+	Count      int64
+	LastUsedAt time.Time
+	LastUsedIP string
+}
@@ -0,0 +1,54 @@
+package apikey
+
+import "time"
+
+type APIKeyFormatter struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	UsageCount int64      `json:"usage_count"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreatedAPIKeyFormatter struct {
+	APIKeyFormatter
+	Key string `json:"key"`
+}
+
+func FormatAPIKey(apiKey APIKey) APIKeyFormatter {
+	formatter := APIKeyFormatter{}
+	formatter.ID = apiKey.ID
+	formatter.Name = apiKey.Name
+	formatter.Prefix = apiKey.Prefix
+	formatter.Scopes = apiKey.Scopes
+	formatter.ExpiresAt = apiKey.ExpiresAt
+	formatter.LastUsedAt = apiKey.LastUsedAt
+	formatter.LastUsedIP = apiKey.LastUsedIP
+	formatter.UsageCount = apiKey.UsageCount
+	formatter.CreatedAt = apiKey.CreatedAt
+
+	return formatter
+}
+
+func FormatAPIKeys(apiKeys []APIKey) []APIKeyFormatter {
+	formatters := []APIKeyFormatter{}
+
+	for _, apiKey := range apiKeys {
+		formatters = append(formatters, FormatAPIKey(apiKey))
+	}
+
+	return formatters
+}
+
+// FormatCreatedAPIKey is the only place the plaintext key is ever shown
+func FormatCreatedAPIKey(apiKey APIKey, plaintext string) CreatedAPIKeyFormatter {
+	formatter := CreatedAPIKeyFormatter{}
+	formatter.APIKeyFormatter = FormatAPIKey(apiKey)
+	formatter.Key = plaintext
+
+	return formatter
+}
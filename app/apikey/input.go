@@ -0,0 +1,9 @@
+package apikey
+
+type (
+	CreateAPIKeyInput struct {
+		Name          string   `json:"name" validate:"required,max=100"`
+		Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=profile:read campaigns:read campaigns:create campaigns:upload transactions:read"`
+		ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+	}
+)
@@ -0,0 +1,205 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	Save(ctx context.Context, apiKey APIKey) (APIKey, error)
+	FindActiveByHash(ctx context.Context, keyHash string) (APIKey, error)
+	FindActiveByUserID(ctx context.Context, userID string) ([]APIKey, error)
+	CountActiveByUserID(ctx context.Context, userID string) (int, error)
+	Revoke(ctx context.Context, ID string, userID string) (bool, error)
+	RecordUsage(ctx context.Context, usage map[string]Usage) error
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+const selectAPIKey = "SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, last_used_ip, usage_count, created_at FROM api_keys"
+
+func NewAPIKeyRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, apiKey APIKey) (APIKey, error) {
+	sqlQuery := "INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return apiKey, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		apiKey.ID,
+		apiKey.UserID,
+		apiKey.Name,
+		apiKey.Prefix,
+		apiKey.KeyHash,
+		pq.Array(apiKey.Scopes),
+		apiKey.ExpiresAt.Format(layoutDateTime),
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return apiKey, err
+	}
+
+	apiKey.CreatedAt = now
+	return apiKey, nil
+}
+
+// FindActiveByHash returns an empty key when the key is unknown, revoked or expired
+func (r *repository) FindActiveByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	apiKeys, err := r.query(ctx, selectAPIKey+" WHERE key_hash = $1 AND revoked_at IS NULL AND expires_at > $2", keyHash, time.Now().Format(layoutDateTime))
+	if err != nil || len(apiKeys) == 0 {
+		return APIKey{}, err
+	}
+
+	return apiKeys[0], nil
+}
+
+func (r *repository) FindActiveByUserID(ctx context.Context, userID string) ([]APIKey, error) {
+	return r.query(ctx, selectAPIKey+" WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2 ORDER BY created_at DESC", userID, time.Now().Format(layoutDateTime))
+}
+
+func (r *repository) CountActiveByUserID(ctx context.Context, userID string) (int, error) {
+	sqlQuery := "SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	defer stmt.Close()
+
+	count := 0
+
+	err = stmt.QueryRowContext(ctx, userID, time.Now().Format(layoutDateTime)).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Revoke returns false when the key doesn't exist, belongs to someone else or is already revoked
+func (r *repository) Revoke(ctx context.Context, ID string, userID string) (bool, error) {
+	sqlQuery := "UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, time.Now().Format(layoutDateTime), ID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) RecordUsage(ctx context.Context, usage map[string]Usage) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for ID, keyUsage := range usage {
+		_, err = tx.ExecContext(ctx, "UPDATE api_keys SET usage_count = usage_count + $1, last_used_at = $2, last_used_ip = $3 WHERE id = $4",
+			keyUsage.Count,
+			keyUsage.LastUsedAt.Format(layoutDateTime),
+			keyUsage.LastUsedIP,
+			ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) query(ctx context.Context, sqlQuery string, args ...interface{}) ([]APIKey, error) {
+	apiKeys := []APIKey{}
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return apiKeys, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return apiKeys, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		apiKey := APIKey{}
+		var expiresAt, createdAt string
+		var lastUsedAt sql.NullString
+
+		err := rows.Scan(
+			&apiKey.ID,
+			&apiKey.UserID,
+			&apiKey.Name,
+			&apiKey.Prefix,
+			&apiKey.KeyHash,
+			pq.Array(&apiKey.Scopes),
+			&expiresAt,
+			&lastUsedAt,
+			&apiKey.LastUsedIP,
+			&apiKey.UsageCount,
+			&createdAt,
+		)
+		if err != nil {
+			return apiKeys, err
+		}
+
+		if apiKey.ExpiresAt, err = time.Parse(time.RFC3339, expiresAt); err != nil {
+			log.Error(err)
+		}
+
+		if apiKey.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if lastUsedAt.Valid {
+			parsed, err := time.Parse(time.RFC3339, lastUsedAt.String)
+			if err != nil {
+				log.Error(err)
+			} else {
+				apiKey.LastUsedAt = &parsed
+			}
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, nil
+}
@@ -0,0 +1,258 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"funding-app/app/helper"
+	"funding-app/app/user"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// keyPrefix makes API keys recognizable, in the Authorization header and in leaked secret scanners
+const keyPrefix = "fak_"
+
+var (
+	ErrInvalidAPIKey   = errors.New("invalid API key")
+	ErrAPIKeyNotFound  = errors.New("API key not found")
+	ErrScopeNotAllowed = errors.New("your role doesn't grant one of the requested scopes")
+	ErrTooManyAPIKeys  = errors.New("too many active API keys, revoke one first")
+)
+
+type Service interface {
+	CreateAPIKey(currentUser user.User, input CreateAPIKeyInput) (APIKey, string, error)
+	GetAPIKeys(userID string) ([]APIKey, error)
+	RevokeAPIKey(ID string, userID string) error
+	Authenticate(plaintext string, ipAddress string) (APIKey, error)
+	Start(ctx context.Context)
+}
+
+type service struct {
+	apiKeyRepository Repository
+	defaultTTL       time.Duration
+	maxPerUser       int
+	flushInterval    time.Duration
+
+	// usage is collected in memory and written in batches, so a busy key doesn't cost a write per request
+	mu    sync.Mutex
+	usage map[string]Usage
+}
+
+func NewAPIKeyService(apiKeyRepository Repository) Service {
+	return &service{
+		apiKeyRepository: apiKeyRepository,
+		defaultTTL:       helper.GetEnvDuration("API_KEY_DEFAULT_TTL", 90*24*time.Hour),
+		maxPerUser:       helper.GetEnvInt("API_KEY_MAX_PER_USER", 20),
+		flushInterval:    helper.GetEnvDuration("API_KEY_USAGE_FLUSH_INTERVAL", 30*time.Second),
+		usage:            map[string]Usage{},
+	}
+}
+
+// IsAPIKey tells API keys apart from JWTs in the Authorization header
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, keyPrefix)
+}
+
+// CreateAPIKey returns the plaintext key next to the stored key, it can't be recovered later
+func (s *service) CreateAPIKey(currentUser user.User, input CreateAPIKeyInput) (APIKey, string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a key never gets more than the role of its owner
+	for _, scope := range input.Scopes {
+		if !currentUser.Can(user.Permission(scope)) {
+			return APIKey{}, "", ErrScopeNotAllowed
+		}
+	}
+
+	count, err := s.apiKeyRepository.CountActiveByUserID(ctx, currentUser.ID)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	if count >= s.maxPerUser {
+		return APIKey{}, "", ErrTooManyAPIKeys
+	}
+
+	plaintext, err := generateKey()
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	ttl := s.defaultTTL
+	if input.ExpiresInDays > 0 {
+		ttl = time.Duration(input.ExpiresInDays) * 24 * time.Hour
+	}
+
+	apiKey := APIKey{}
+	apiKey.ID = helper.GenerateID()
+	apiKey.UserID = currentUser.ID
+	apiKey.Name = strings.TrimSpace(input.Name)
+	apiKey.Prefix = plaintext[:len(keyPrefix)+8]
+	apiKey.KeyHash = hashKey(plaintext)
+	apiKey.Scopes = uniqueScopes(input.Scopes)
+	apiKey.ExpiresAt = time.Now().Add(ttl)
+
+	newAPIKey, err := s.apiKeyRepository.Save(ctx, apiKey)
+	if err != nil {
+		return newAPIKey, "", err
+	}
+
+	return newAPIKey, plaintext, nil
+}
+
+func (s *service) GetAPIKeys(userID string) ([]APIKey, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	apiKeys, err := s.apiKeyRepository.FindActiveByUserID(ctx, userID)
+	if err != nil {
+		return apiKeys, err
+	}
+
+	// show usage that hasn't been flushed yet
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, apiKey := range apiKeys {
+		if keyUsage, ok := s.usage[apiKey.ID]; ok {
+			lastUsedAt := keyUsage.LastUsedAt
+			apiKeys[i].LastUsedAt = &lastUsedAt
+			apiKeys[i].LastUsedIP = keyUsage.LastUsedIP
+			apiKeys[i].UsageCount += keyUsage.Count
+		}
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey only revokes keys of the given user
+func (s *service) RevokeAPIKey(ID string, userID string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	revoked, err := s.apiKeyRepository.Revoke(ctx, ID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// Authenticate looks up an active key and counts the request against it
+func (s *service) Authenticate(plaintext string, ipAddress string) (APIKey, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !IsAPIKey(plaintext) {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyRepository.FindActiveByHash(ctx, hashKey(plaintext))
+	if err != nil {
+		return apiKey, err
+	}
+
+	if apiKey.ID == "" {
+		return apiKey, ErrInvalidAPIKey
+	}
+
+	s.mu.Lock()
+	keyUsage := s.usage[apiKey.ID]
+	keyUsage.Count++
+	keyUsage.LastUsedAt = time.Now()
+	keyUsage.LastUsedIP = ipAddress
+	s.usage[apiKey.ID] = keyUsage
+	s.mu.Unlock()
+
+	return apiKey, nil
+}
+
+// Start writes the collected usage periodically and once more on shutdown
+func (s *service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.flush(context.Background())
+				return
+			case <-ticker.C:
+				s.flush(ctx)
+			}
+		}
+	}()
+}
+
+func (s *service) flush(ctx context.Context) {
+	s.mu.Lock()
+	usage := s.usage
+	s.usage = map[string]Usage{}
+	s.mu.Unlock()
+
+	if len(usage) == 0 {
+		return
+	}
+
+	err := s.apiKeyRepository.RecordUsage(ctx, usage)
+	if err != nil {
+		log.Error(err)
+
+		// keep the counts for the next try, merged with whatever came in meanwhile
+		s.mu.Lock()
+		for ID, keyUsage := range usage {
+			current, ok := s.usage[ID]
+			if ok {
+				keyUsage.Count += current.Count
+				keyUsage.LastUsedAt = current.LastUsedAt
+				keyUsage.LastUsedIP = current.LastUsedIP
+			}
+
+			s.usage[ID] = keyUsage
+		}
+		s.mu.Unlock()
+	}
+}
+
+func uniqueScopes(scopes []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+
+	return unique
+}
+
+func generateKey() (string, error) {
+	buf := make([]byte, 32)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ActionUserSuspend          = "user.suspend"
+	ActionUserUnsuspend        = "user.unsuspend"
+	ActionUserUnlock           = "user.unlock"
+	ActionUserRoleChange       = "user.role_change"
+	ActionUserTransactionsView = "user.transactions_view"
+	ActionCampaignClose        = "campaign.close"
+	ActionCampaignUnpublish    = "campaign.unpublish"
+	ActionCampaignPublish      = "campaign.publish"
+
+	TargetUser     = "user"
+	TargetCampaign = "campaign"
+)
+
+type (
+	// Metadata is stored as JSONB
+	Metadata map[string]interface{}
+
+	Log struct {
+		ID         string
+		ActorID    string
+		Action     string
+		TargetType string
+		TargetID   string
+		Metadata   Metadata
+		IPAddress  string
+		CreatedAt  time.Time
+	}
+)
+
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(m)
+}
+
+func (m *Metadata) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		*m = Metadata{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, m)
+	case string:
+		return json.Unmarshal([]byte(data), m)
+	default:
+		return fmt.Errorf("unsupported metadata type %T", src)
+	}
+}
@@ -0,0 +1,38 @@
+package audit
+
+import "time"
+
+type LogFormatter struct {
+	ID         string    `json:"id"`
+	ActorID    string    `json:"actor_id"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	Metadata   Metadata  `json:"metadata"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func FormatLog(log Log) LogFormatter {
+	formatter := LogFormatter{}
+	formatter.ID = log.ID
+	formatter.ActorID = log.ActorID
+	formatter.Action = log.Action
+	formatter.TargetType = log.TargetType
+	formatter.TargetID = log.TargetID
+	formatter.Metadata = log.Metadata
+	formatter.IPAddress = log.IPAddress
+	formatter.CreatedAt = log.CreatedAt
+
+	return formatter
+}
+
+func FormatLogs(logs []Log) []LogFormatter {
+	formatter := []LogFormatter{}
+
+	for _, log := range logs {
+		formatter = append(formatter, FormatLog(log))
+	}
+
+	return formatter
+}
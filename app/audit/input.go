@@ -0,0 +1,20 @@
+package audit
+
+type (
+	RecordInput struct {
+		ActorID    string
+		Action     string
+		TargetType string
+		TargetID   string
+		Metadata   Metadata
+		IPAddress  string
+	}
+
+	GetLogsInput struct {
+		ActorID  string
+		TargetID string
+		Action   string
+		Page     int `validate:"min=1"`
+		PerPage  int `validate:"min=1,max=100"`
+	}
+)
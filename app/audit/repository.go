@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	Save(ctx context.Context, auditLog Log) (Log, error)
+	FindAll(ctx context.Context, input GetLogsInput) ([]Log, int, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewAuditRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, auditLog Log) (Log, error) {
+	sqlQuery := "INSERT INTO audit_logs (id, actor_id, action, target_type, target_id, metadata, ip_address, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return auditLog, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		auditLog.ID,
+		auditLog.ActorID,
+		auditLog.Action,
+		auditLog.TargetType,
+		auditLog.TargetID,
+		auditLog.Metadata,
+		auditLog.IPAddress,
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return auditLog, err
+	}
+
+	auditLog.CreatedAt = now
+	return auditLog, nil
+}
+
+func (r *repository) FindAll(ctx context.Context, input GetLogsInput) ([]Log, int, error) {
+	auditLogs := []Log{}
+	total := 0
+
+	sqlQuery := `SELECT id, actor_id, action, target_type, target_id, metadata, ip_address, created_at, COUNT(*) OVER()
+		FROM audit_logs
+		WHERE ($1 = '' OR actor_id = $1)
+			AND ($2 = '' OR target_id = $2)
+			AND ($3 = '' OR action = $3)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5`
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return auditLogs, total, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, input.ActorID, input.TargetID, input.Action, input.PerPage, (input.Page-1)*input.PerPage)
+	if err != nil {
+		return auditLogs, total, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		auditLog := Log{}
+		var createdAt string
+
+		err := rows.Scan(
+			&auditLog.ID,
+			&auditLog.ActorID,
+			&auditLog.Action,
+			&auditLog.TargetType,
+			&auditLog.TargetID,
+			&auditLog.Metadata,
+			&auditLog.IPAddress,
+			&createdAt,
+			&total,
+		)
+		if err != nil {
+			return auditLogs, total, err
+		}
+
+		if auditLog.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	return auditLogs, total, nil
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"funding-app/app/helper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Service interface {
+	Record(input RecordInput) error
+	GetLogs(input GetLogsInput) ([]Log, int, error)
+}
+
+type service struct {
+	auditRepository Repository
+}
+
+func NewAuditService(auditRepository Repository) Service {
+	return &service{auditRepository}
+}
+
+// Record stores the action, if that fails the entry still ends up in the application log
+func (s *service) Record(input RecordInput) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	auditLog := Log{}
+	auditLog.ID = helper.GenerateID()
+	auditLog.ActorID = input.ActorID
+	auditLog.Action = input.Action
+	auditLog.TargetType = input.TargetType
+	auditLog.TargetID = input.TargetID
+	auditLog.Metadata = input.Metadata
+	auditLog.IPAddress = input.IPAddress
+
+	_, err := s.auditRepository.Save(ctx, auditLog)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"actor_id":    input.ActorID,
+			"action":      input.Action,
+			"target_type": input.TargetType,
+			"target_id":   input.TargetID,
+			"metadata":    input.Metadata,
+		}).Error("failed to record audit log: ", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetLogs(input GetLogsInput) ([]Log, int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	return s.auditRepository.FindAll(ctx, input)
+}
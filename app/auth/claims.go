@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"funding-app/app/key"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Claims are the claims of an access token, the subject is the user ID and the ID is the jti
+type Claims struct {
+	Role         string `json:"role"`
+	SessionID    string `json:"sid,omitempty"`
+	TokenVersion int    `json:"token_version"`
+	jwt.RegisteredClaims
+}
+
+type TokenConfig struct {
+	Issuer          string
+	Audience        string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	ClockSkew       time.Duration
+}
+
+// Session identifies the session and the access token the claims belong to
+func (c Claims) Session() key.Session {
+	session := key.Session{}
+	session.ID = c.SessionID
+	session.TokenID = c.ID
+
+	if c.ExpiresAt != nil {
+		session.ExpiresAt = c.ExpiresAt.Time
+	}
+
+	return session
+}
+
+// validate checks the time based claims with the allowed clock skew, then issuer and audience
+func (c Claims) validate(config TokenConfig) error {
+	now := jwt.TimeFunc()
+
+	if !c.VerifyExpiresAt(now.Add(-config.ClockSkew), true) {
+		return ErrInvalidToken
+	}
+
+	if !c.VerifyIssuedAt(now.Add(config.ClockSkew), false) {
+		return ErrInvalidToken
+	}
+
+	if !c.VerifyNotBefore(now.Add(config.ClockSkew), false) {
+		return ErrInvalidToken
+	}
+
+	if !c.VerifyIssuer(config.Issuer, true) || !c.VerifyAudience(config.Audience, true) {
+		return ErrInvalidToken
+	}
+
+	if c.Subject == "" {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
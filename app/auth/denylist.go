@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Denylist keeps revoked token and session IDs in memory until the access tokens carrying them expire,
+// so the auth middleware can reject them without a database round trip
+type Denylist struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewDenylist() *Denylist {
+	return &Denylist{entries: map[string]time.Time{}}
+}
+
+func (d *Denylist) Add(ID string, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if current, ok := d.entries[ID]; ok && current.After(until) {
+		return
+	}
+
+	d.entries[ID] = until
+}
+
+func (d *Denylist) Contains(ID string) bool {
+	if ID == "" {
+		return false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	until, ok := d.entries[ID]
+	return ok && time.Now().Before(until)
+}
+
+// Prune drops entries whose tokens have expired anyway
+func (d *Denylist) Prune() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for ID, until := range d.entries {
+		if !now.Before(until) {
+			delete(d.entries, ID)
+		}
+	}
+}
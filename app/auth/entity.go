@@ -0,0 +1,37 @@
+package auth
+
+import "time"
+
+type RefreshToken struct {
+	ID           string
+	FamilyID     string
+	UserID       string
+	TokenHash    string
+	TokenVersion int
+	ExpiresAt    time.Time
+	Expired      bool
+	Rotated      bool
+	Revoked      bool
+	CreatedAt    time.Time
+}
+
+// Session is a login on one device, its refresh tokens all share the session ID as family ID
+type Session struct {
+	ID         string
+	UserID     string
+	Device     string
+	IPAddress  string
+	UserAgent  string
+	LastSeenAt time.Time
+	CreatedAt  time.Time
+}
+
+// SigningKey is an asymmetric key pair used to sign access tokens, the private key is stored encrypted
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  string
+	Published   bool
+	RotationDue bool
+	CreatedAt   time.Time
+}
@@ -0,0 +1,36 @@
+package auth
+
+import "time"
+
+type SessionFormatter struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func FormatSession(session Session, currentSessionID string) SessionFormatter {
+	formatter := SessionFormatter{}
+	formatter.ID = session.ID
+	formatter.Device = session.Device
+	formatter.IPAddress = session.IPAddress
+	formatter.UserAgent = session.UserAgent
+	formatter.Current = session.ID == currentSessionID
+	formatter.LastSeenAt = session.LastSeenAt
+	formatter.CreatedAt = session.CreatedAt
+
+	return formatter
+}
+
+func FormatSessions(sessions []Session, currentSessionID string) []SessionFormatter {
+	sessionsFormatter := []SessionFormatter{}
+
+	for _, session := range sessions {
+		sessionsFormatter = append(sessionsFormatter, FormatSession(session, currentSessionID))
+	}
+
+	return sessionsFormatter
+}
@@ -0,0 +1,12 @@
+package auth
+
+type (
+	RefreshTokenInput struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	SessionInput struct {
+		IPAddress string
+		UserAgent string
+	}
+)
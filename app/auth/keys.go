@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"funding-app/app/helper"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrNoSigningKey  = errors.New("no signing key available")
+	ErrUnknownKeyID  = errors.New("unknown signing key")
+	ErrKeyAlgorithm  = errors.New("unsupported signing algorithm")
+	ErrMissingSecret = errors.New("SECRET_KEY must be set to sign tokens with HS256")
+	ErrMissingKey    = errors.New("JWT_KEY_ENCRYPTION_KEY or SECRET_KEY must be set")
+	errCorruptedKeys = errors.New("signing key can't be decrypted")
+)
+
+type (
+	KeyConfig struct {
+		Algorithm        string
+		SecretKey        []byte
+		EncryptionKey    []byte
+		RotationInterval time.Duration
+		GracePeriod      time.Duration
+		PublishDelay     time.Duration
+		RefreshInterval  time.Duration
+	}
+
+	JWK struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		Crv string `json:"crv,omitempty"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		X   string `json:"x,omitempty"`
+	}
+
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+)
+
+// keyRing holds the decrypted keys in memory, the database is the source of truth shared by every instance
+type keyRing struct {
+	config     KeyConfig
+	repository Repository
+
+	mu         sync.RWMutex
+	signingID  string
+	privateKey crypto.Signer
+	publicKeys map[string]crypto.PublicKey
+	loadedAt   time.Time
+
+	// loading is closed when the load in flight finishes
+	loadMu  sync.Mutex
+	loading chan struct{}
+}
+
+func newKeyRing(repository Repository, config KeyConfig) *keyRing {
+	return &keyRing{
+		config:     config,
+		repository: repository,
+		publicKeys: map[string]crypto.PublicKey{},
+	}
+}
+
+func (k *keyRing) symmetric() bool {
+	return k.config.Algorithm == AlgorithmHS256
+}
+
+func (k *keyRing) signingMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.config.Algorithm)
+}
+
+// sign returns the key to sign with and its kid, the HMAC secret has no kid
+func (k *keyRing) sign() (string, interface{}, error) {
+	if k.symmetric() {
+		return "", k.config.SecretKey, nil
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.privateKey == nil {
+		return "", nil, ErrNoSigningKey
+	}
+
+	return k.signingID, k.privateKey, nil
+}
+
+// verify looks up the public key of a token, unknown kids reload the keys once in a while
+// because another instance may have rotated in the meantime
+func (k *keyRing) verify(ctx context.Context, kid string) (interface{}, error) {
+	if k.symmetric() {
+		return k.config.SecretKey, nil
+	}
+
+	publicKey, ok, stale := k.lookup(kid)
+	if !ok && stale {
+		k.load(ctx)
+		publicKey, ok, _ = k.lookup(kid)
+	}
+
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	return publicKey, nil
+}
+
+func (k *keyRing) lookup(kid string) (crypto.PublicKey, bool, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	publicKey, ok := k.publicKeys[kid]
+	return publicKey, ok, time.Since(k.loadedAt) > 5*time.Second
+}
+
+// load reads the keys from the database and rotates when the signing key is due, a burst of
+// tokens with an unknown kid shares the load in flight instead of starting one each
+func (k *keyRing) load(ctx context.Context) {
+	if k.symmetric() {
+		return
+	}
+
+	k.loadMu.Lock()
+	if loading := k.loading; loading != nil {
+		k.loadMu.Unlock()
+
+		select {
+		case <-loading:
+		case <-ctx.Done():
+		}
+		return
+	}
+
+	loading := make(chan struct{})
+	k.loading = loading
+	k.loadMu.Unlock()
+
+	defer func() {
+		k.loadMu.Lock()
+		k.loading = nil
+		k.loadMu.Unlock()
+
+		close(loading)
+	}()
+
+	k.reload(ctx)
+}
+
+func (k *keyRing) reload(ctx context.Context) {
+	now := time.Now()
+	signingKeys, err := k.findSigningKeys(ctx, now)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	// the newest key is always ahead of schedule, so a due newest key means it's time for a new one
+	if len(signingKeys) == 0 || signingKeys[0].RotationDue {
+		err := k.generate(ctx, now.Add(-k.config.RotationInterval))
+		if err != nil {
+			log.Error(err)
+		} else {
+			// the new key is ours or the one of an instance that rotated first
+			signingKeys, err = k.findSigningKeys(ctx, now)
+			if err != nil {
+				log.Error(err)
+				return
+			}
+		}
+	}
+
+	publicKeys := map[string]crypto.PublicKey{}
+	var signing *SigningKey
+	var privateKey crypto.Signer
+
+	for i, signingKey := range signingKeys {
+		decrypted, err := k.decrypt(signingKey.PrivateKey)
+		if err != nil {
+			log.WithField("kid", signingKey.ID).Error(err)
+			continue
+		}
+
+		publicKeys[signingKey.ID] = decrypted.Public()
+
+		// sign with the newest key every verifier had the chance to fetch, or the newest at all on first start
+		if signing == nil && (signingKey.Published || i == len(signingKeys)-1) {
+			signing = &signingKeys[i]
+			privateKey = decrypted
+		}
+	}
+
+	if signing != nil && len(signingKeys) > 1 {
+		err = k.repository.RetireSigningKeys(ctx, k.config.Algorithm, signing.ID)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if signing != nil {
+		k.signingID = signing.ID
+		k.privateKey = privateKey
+	}
+
+	k.publicKeys = publicKeys
+	k.loadedAt = time.Now()
+}
+
+func (k *keyRing) findSigningKeys(ctx context.Context, now time.Time) ([]SigningKey, error) {
+	return k.repository.FindSigningKeys(ctx,
+		k.config.Algorithm,
+		now.Add(-k.config.GracePeriod),
+		now.Add(-k.config.PublishDelay),
+		now.Add(-k.config.RotationInterval),
+	)
+}
+
+// generate stores a new key unless another instance stored one after rotateBefore
+func (k *keyRing) generate(ctx context.Context, rotateBefore time.Time) error {
+	var privateKey crypto.Signer
+	var err error
+
+	switch k.config.Algorithm {
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return ErrKeyAlgorithm
+	}
+
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := k.encrypt(der)
+	if err != nil {
+		return err
+	}
+
+	signingKey := SigningKey{}
+	signingKey.ID = helper.GenerateID()
+	signingKey.Algorithm = k.config.Algorithm
+	signingKey.PrivateKey = encrypted
+
+	isSaved, err := k.repository.SaveSigningKey(ctx, signingKey, rotateBefore)
+	if err != nil {
+		return err
+	}
+
+	if isSaved {
+		log.WithField("kid", signingKey.ID).Info("generated a new token signing key")
+	}
+
+	return nil
+}
+
+func (k *keyRing) encrypt(plain []byte) (string, error) {
+	return helper.Encrypt(k.config.EncryptionKey, plain)
+}
+
+func (k *keyRing) decrypt(encrypted string) (crypto.Signer, error) {
+	der, err := helper.Decrypt(k.config.EncryptionKey, encrypted)
+	if err != nil {
+		return nil, errCorruptedKeys
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, ErrKeyAlgorithm
+	}
+
+	return signer, nil
+}
+
+// jwks publishes every key that is still valid for verification
+func (k *keyRing) jwks() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for kid, publicKey := range k.publicKeys {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: k.config.Algorithm}
+
+		switch publicKey := publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingRepository holds every read of the keys until release is closed
+type blockingRepository struct {
+	Repository
+	reads   int32
+	release chan struct{}
+}
+
+func (r *blockingRepository) FindSigningKeys(ctx context.Context, algorithm string, retiredSince time.Time, publishedBefore time.Time, rotateBefore time.Time) ([]SigningKey, error) {
+	atomic.AddInt32(&r.reads, 1)
+	<-r.release
+
+	return []SigningKey{}, nil
+}
+
+func (r *blockingRepository) SaveSigningKey(ctx context.Context, signingKey SigningKey, rotateBefore time.Time) (bool, error) {
+	// another instance was faster
+	return false, nil
+}
+
+func TestLoadSharesTheLoadInFlight(t *testing.T) {
+	repository := &blockingRepository{release: make(chan struct{})}
+	keys := newKeyRing(repository, KeyConfig{Algorithm: AlgorithmEdDSA, EncryptionKey: []byte("test-encryption-key")})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys.verify(context.Background(), "unknown")
+		}()
+	}
+
+	// let every caller reach load before the first read returns
+	time.Sleep(50 * time.Millisecond)
+	close(repository.release)
+	wg.Wait()
+
+	// one read, then one more after the rotation that lost to another instance
+	if reads := atomic.LoadInt32(&repository.reads); reads != 2 {
+		t.Errorf("keys were read %d times, want 2", reads)
+	}
+}
@@ -0,0 +1,414 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	Save(ctx context.Context, refreshToken RefreshToken) (RefreshToken, error)
+	FindByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	MarkRotated(ctx context.Context, ID string) (bool, error)
+	SaveSession(ctx context.Context, session Session) (Session, error)
+	FindSessionByID(ctx context.Context, ID string) (Session, error)
+	FindActiveSessionsByUserID(ctx context.Context, userID string) ([]Session, error)
+	FindSessionsByUserID(ctx context.Context, userID string) ([]Session, error)
+	FindRevokedSessionIDsSince(ctx context.Context, since time.Time) ([]string, error)
+	TouchSession(ctx context.Context, session Session) error
+	RevokeSession(ctx context.Context, ID string) error
+	SaveSigningKey(ctx context.Context, signingKey SigningKey, rotateBefore time.Time) (bool, error)
+	FindSigningKeys(ctx context.Context, algorithm string, retiredSince time.Time, publishedBefore time.Time, rotateBefore time.Time) ([]SigningKey, error)
+	RetireSigningKeys(ctx context.Context, algorithm string, exceptID string) error
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewRefreshTokenRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, refreshToken RefreshToken) (RefreshToken, error) {
+	sqlQuery := "INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, token_version, expires_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return refreshToken, err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx,
+		refreshToken.ID,
+		refreshToken.FamilyID,
+		refreshToken.UserID,
+		refreshToken.TokenHash,
+		refreshToken.TokenVersion,
+		refreshToken.ExpiresAt.Format(layoutDateTime),
+		time.Now().Format(layoutDateTime),
+	)
+	if err != nil {
+		return refreshToken, err
+	}
+
+	return refreshToken, nil
+}
+
+// FindByHash compares the expiry in the database so it uses the same clock the row was written with
+func (r *repository) FindByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	refreshToken := RefreshToken{}
+
+	sqlQuery := "SELECT id, family_id, user_id, token_hash, token_version, expires_at <= $1, rotated_at IS NOT NULL, revoked_at IS NOT NULL FROM refresh_tokens WHERE token_hash = $2"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return refreshToken, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, time.Now().Format(layoutDateTime), tokenHash).Scan(
+		&refreshToken.ID,
+		&refreshToken.FamilyID,
+		&refreshToken.UserID,
+		&refreshToken.TokenHash,
+		&refreshToken.TokenVersion,
+		&refreshToken.Expired,
+		&refreshToken.Rotated,
+		&refreshToken.Revoked,
+	)
+	if err == sql.ErrNoRows {
+		return RefreshToken{}, nil
+	}
+
+	if err != nil {
+		return refreshToken, err
+	}
+
+	return refreshToken, nil
+}
+
+// MarkRotated returns false when another request already used the token
+func (r *repository) MarkRotated(ctx context.Context, ID string) (bool, error) {
+	sqlQuery := "UPDATE refresh_tokens SET rotated_at = $1 WHERE id = $2 AND rotated_at IS NULL AND revoked_at IS NULL"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, time.Now().Format(layoutDateTime), ID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) SaveSession(ctx context.Context, session Session) (Session, error) {
+	sqlQuery := "INSERT INTO sessions (id, user_id, device, ip_address, user_agent, last_seen_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return session, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		session.ID,
+		session.UserID,
+		session.Device,
+		session.IPAddress,
+		session.UserAgent,
+		now.Format(layoutDateTime),
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return session, err
+	}
+
+	session.LastSeenAt = now
+	session.CreatedAt = now
+
+	return session, nil
+}
+
+// FindSessionByID only returns sessions that are not revoked yet
+func (r *repository) FindSessionByID(ctx context.Context, ID string) (Session, error) {
+	sqlQuery := "SELECT id, user_id, device, ip_address, user_agent, last_seen_at, created_at FROM sessions WHERE id = $1 AND revoked_at IS NULL"
+
+	sessions, err := r.querySessions(ctx, sqlQuery, ID)
+	if err != nil || len(sessions) == 0 {
+		return Session{}, err
+	}
+
+	return sessions[0], nil
+}
+
+// FindActiveSessionsByUserID lists the sessions that still hold a usable refresh token
+func (r *repository) FindActiveSessionsByUserID(ctx context.Context, userID string) ([]Session, error) {
+	sqlQuery := `SELECT s.id, s.user_id, s.device, s.ip_address, s.user_agent, s.last_seen_at, s.created_at FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL AND EXISTS (
+			SELECT 1 FROM refresh_tokens t WHERE t.family_id = s.id AND t.rotated_at IS NULL AND t.revoked_at IS NULL AND t.expires_at > $2 AND t.token_version = u.token_version
+		)
+		ORDER BY s.last_seen_at DESC`
+
+	return r.querySessions(ctx, sqlQuery, userID, time.Now().Format(layoutDateTime))
+}
+
+// FindSessionsByUserID lists every session including revoked and expired ones
+func (r *repository) FindSessionsByUserID(ctx context.Context, userID string) ([]Session, error) {
+	sqlQuery := "SELECT s.id, s.user_id, s.device, s.ip_address, s.user_agent, s.last_seen_at, s.created_at FROM sessions s WHERE s.user_id = $1 ORDER BY s.created_at DESC"
+
+	return r.querySessions(ctx, sqlQuery, userID)
+}
+
+func (r *repository) FindRevokedSessionIDsSince(ctx context.Context, since time.Time) ([]string, error) {
+	sessionIDs := []string{}
+
+	sqlQuery := "SELECT id FROM sessions WHERE revoked_at >= $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return sessionIDs, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, since.Format(layoutDateTime))
+	if err != nil {
+		return sessionIDs, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ID string
+
+		err := rows.Scan(&ID)
+		if err != nil {
+			return sessionIDs, err
+		}
+
+		sessionIDs = append(sessionIDs, ID)
+	}
+
+	return sessionIDs, nil
+}
+
+func (r *repository) TouchSession(ctx context.Context, session Session) error {
+	sqlQuery := "UPDATE sessions SET ip_address = $1, user_agent = $2, device = $3, last_seen_at = $4 WHERE id = $5"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx,
+		session.IPAddress,
+		session.UserAgent,
+		session.Device,
+		time.Now().Format(layoutDateTime),
+		session.ID,
+	)
+	return err
+}
+
+// RevokeSession revokes the session together with every refresh token of its family
+func (r *repository) RevokeSession(ctx context.Context, ID string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	now := time.Now().Format(layoutDateTime)
+
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL", now, ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL", now, ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveSigningKey stores the key unless another instance already stored one created after rotateBefore,
+// the advisory lock makes instances that rotate at the same time take turns
+func (r *repository) SaveSigningKey(ctx context.Context, signingKey SigningKey, rotateBefore time.Time) (bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('signing_keys:' || $1))", signingKey.Algorithm)
+	if err != nil {
+		return false, err
+	}
+
+	var isFresh bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM signing_keys WHERE algorithm = $1 AND created_at > $2)",
+		signingKey.Algorithm,
+		rotateBefore.Format(layoutDateTime),
+	).Scan(&isFresh)
+	if err != nil {
+		return false, err
+	}
+
+	if isFresh {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO signing_keys (id, algorithm, private_key, created_at) VALUES($1, $2, $3, $4)",
+		signingKey.ID,
+		signingKey.Algorithm,
+		signingKey.PrivateKey,
+		time.Now().Format(layoutDateTime),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// FindSigningKeys returns the keys still valid for verification, newest first
+func (r *repository) FindSigningKeys(ctx context.Context, algorithm string, retiredSince time.Time, publishedBefore time.Time, rotateBefore time.Time) ([]SigningKey, error) {
+	signingKeys := []SigningKey{}
+
+	sqlQuery := "SELECT id, algorithm, private_key, created_at <= $1, created_at <= $2, created_at FROM signing_keys WHERE algorithm = $3 AND (retired_at IS NULL OR retired_at > $4) ORDER BY created_at DESC"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return signingKeys, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx,
+		publishedBefore.Format(layoutDateTime),
+		rotateBefore.Format(layoutDateTime),
+		algorithm,
+		retiredSince.Format(layoutDateTime),
+	)
+	if err != nil {
+		return signingKeys, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		signingKey := SigningKey{}
+		var createdAt string
+
+		err := rows.Scan(
+			&signingKey.ID,
+			&signingKey.Algorithm,
+			&signingKey.PrivateKey,
+			&signingKey.Published,
+			&signingKey.RotationDue,
+			&createdAt,
+		)
+		if err != nil {
+			return signingKeys, err
+		}
+
+		if signingKey.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		signingKeys = append(signingKeys, signingKey)
+	}
+
+	return signingKeys, nil
+}
+
+// RetireSigningKeys starts the grace period of every key older than the given one
+func (r *repository) RetireSigningKeys(ctx context.Context, algorithm string, exceptID string) error {
+	sqlQuery := "UPDATE signing_keys SET retired_at = $1 WHERE algorithm = $2 AND id <> $3 AND retired_at IS NULL AND created_at <= (SELECT created_at FROM signing_keys WHERE id = $3)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, time.Now().Format(layoutDateTime), algorithm, exceptID)
+	return err
+}
+
+func (r *repository) querySessions(ctx context.Context, sqlQuery string, args ...interface{}) ([]Session, error) {
+	sessions := []Session{}
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return sessions, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return sessions, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		session := Session{}
+		var lastSeenAt, createdAt string
+
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.Device,
+			&session.IPAddress,
+			&session.UserAgent,
+			&lastSeenAt,
+			&createdAt,
+		)
+		if err != nil {
+			return sessions, err
+		}
+
+		if session.LastSeenAt, err = time.Parse(time.RFC3339, lastSeenAt); err != nil {
+			log.Error(err)
+		}
+
+		if session.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
@@ -1,147 +1,477 @@
 package auth
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"context"
 	"crypto/rand"
-	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"fmt"
+	"funding-app/app/helper"
 	"funding-app/app/key"
 	"funding-app/app/user"
-	"io"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, please login again")
+	ErrSessionNotFound     = errors.New("no session found")
+)
+
 type Service interface {
-	GenerateToken(userID string) (key.Token, error)
-	GenerateRefreshToken(token key.Token) (key.Token, error)
-	ValidateToken(encodedToken string) (*jwt.Token, error)
-	ValidateRefreshToken(token key.Token) (user.User, error)
+	GenerateToken(user user.User, session SessionInput) (key.Token, error)
+	ValidateToken(encodedToken string) (Claims, error)
+	RefreshToken(input RefreshTokenInput, session SessionInput) (key.Token, error)
+	IsRevoked(session key.Session) bool
+	Logout(session key.Session) error
+	GetSessions(userID string) ([]Session, error)
+	GetSessionHistory(userID string) ([]Session, error)
+	RevokeSession(ID string, userID string) error
+	JWKS() JWKS
+	Start(ctx context.Context)
+}
+
+type jwtService struct {
+	refreshTokenRepository Repository
+	userService            user.Service
+	denylist               *Denylist
+	config                 TokenConfig
+	keys                   *keyRing
+	denylistSyncInterval   time.Duration
 }
 
-type jwtService struct{}
+func NewJwtService(refreshTokenRepository Repository, userService user.Service) (Service, error) {
+	config := TokenConfig{
+		Issuer:          helper.GetEnv("JWT_ISSUER", "funding-app"),
+		Audience:        helper.GetEnv("JWT_AUDIENCE", "funding-app"),
+		AccessTokenTTL:  helper.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: helper.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		ClockSkew:       helper.GetEnvDuration("JWT_CLOCK_SKEW", 30*time.Second),
+	}
+
+	keyConfig := KeyConfig{
+		Algorithm:        helper.GetEnv("JWT_SIGNING_ALGORITHM", AlgorithmRS256),
+		SecretKey:        []byte(os.Getenv("SECRET_KEY")),
+		EncryptionKey:    []byte(helper.GetEnv("JWT_KEY_ENCRYPTION_KEY", os.Getenv("SECRET_KEY"))),
+		RotationInterval: helper.GetEnvDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		GracePeriod:      helper.GetEnvDuration("JWT_KEY_GRACE_PERIOD", 24*time.Hour),
+		PublishDelay:     helper.GetEnvDuration("JWT_KEY_PUBLISH_DELAY", 10*time.Minute),
+		RefreshInterval:  helper.GetEnvDuration("JWT_KEY_REFRESH_INTERVAL", time.Minute),
+	}
+
+	// a retired key has to outlive every token it signed, including ones signed by instances that haven't reloaded yet
+	minGracePeriod := config.AccessTokenTTL + config.ClockSkew + keyConfig.RefreshInterval
+	if keyConfig.GracePeriod < minGracePeriod {
+		keyConfig.GracePeriod = minGracePeriod
+	}
+
+	switch keyConfig.Algorithm {
+	case AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		log.Warnf("unsupported JWT_SIGNING_ALGORITHM %q, falling back to %s", keyConfig.Algorithm, AlgorithmRS256)
+		keyConfig.Algorithm = AlgorithmRS256
+	}
+
+	// HS256 signs with the secret itself, the other algorithms keep their private keys encrypted with it
+	if keyConfig.Algorithm == AlgorithmHS256 && len(keyConfig.SecretKey) == 0 {
+		return nil, ErrMissingSecret
+	}
+
+	if keyConfig.Algorithm != AlgorithmHS256 && len(keyConfig.EncryptionKey) == 0 {
+		return nil, ErrMissingKey
+	}
 
-func NewJwtService() Service {
-	return &jwtService{}
+	return &jwtService{
+		refreshTokenRepository: refreshTokenRepository,
+		userService:            userService,
+		denylist:               NewDenylist(),
+		config:                 config,
+		keys:                   newKeyRing(refreshTokenRepository, keyConfig),
+		denylistSyncInterval:   helper.GetEnvDuration("SESSION_DENYLIST_SYNC_INTERVAL", 5*time.Second),
+	}, nil
 }
 
-var (
-	secretKey  = os.Getenv("SECRET_KEY")
-	SECRET_KEY = []byte(secretKey)
-)
+// GenerateToken starts a new session, its ID is the family ID of the refresh tokens
+func (s *jwtService) GenerateToken(user user.User, input SessionInput) (key.Token, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-func (s *jwtService) GenerateToken(userID string) (key.Token, error) {
-	var err error
+	session := Session{}
+	session.ID = helper.GenerateID()
+	session.UserID = user.ID
+	session.IPAddress = input.IPAddress
+	session.UserAgent = input.UserAgent
+	session.Device = deviceName(input.UserAgent)
 
-	claim := jwt.MapClaims{}
-	claim["user_id"] = userID
-	claim["exp"] = time.Now().Add(time.Second * 10).Unix()
+	_, err := s.refreshTokenRepository.SaveSession(ctx, session)
+	if err != nil {
+		return key.Token{}, err
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
-	jwtToken := key.Token{}
+	jwtToken, err := s.generateAccessToken(user, session.ID)
+	if err != nil {
+		return jwtToken, err
+	}
 
-	jwtToken.AccessToken, err = token.SignedString(SECRET_KEY)
+	jwtToken.RefreshToken, err = s.issueRefreshToken(ctx, session.ID, user.ID, user.TokenVersion)
 	if err != nil {
 		return jwtToken, err
 	}
 
-	return s.GenerateRefreshToken(jwtToken)
+	return jwtToken, nil
 }
 
-func (s *jwtService) ValidateToken(encodedToken string) (*jwt.Token, error) {
-	token, err := jwt.Parse(encodedToken, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid token")
-		}
+func (s *jwtService) ValidateToken(encodedToken string) (Claims, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	claims := Claims{}
+
+	// claims are validated below, with the configured clock skew
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{s.keys.config.Algorithm}), jwt.WithoutClaimsValidation())
 
-		return SECRET_KEY, nil
+	_, err := parser.ParseWithClaims(encodedToken, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.keys.verify(ctx, kid)
 	})
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
 
+	err = claims.validate(s.config)
 	if err != nil {
-		return token, err
+		return claims, err
 	}
 
-	return token, nil
+	return claims, nil
 }
 
-func (s *jwtService) GenerateRefreshToken(token key.Token) (key.Token, error) {
-	sha1 := sha1.New()
-	io.WriteString(sha1, os.Getenv("SECRET_KEY"))
+// RefreshToken rotates the refresh token, presenting an already rotated token revokes its whole family
+func (s *jwtService) RefreshToken(input RefreshTokenInput, sessionInput SessionInput) (key.Token, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jwtToken := key.Token{}
+
+	refreshToken, err := s.refreshTokenRepository.FindByHash(ctx, hashRefreshToken(input.RefreshToken))
+	if err != nil {
+		return jwtToken, err
+	}
+
+	if refreshToken.ID == "" || refreshToken.Revoked || refreshToken.Expired {
+		return jwtToken, ErrInvalidRefreshToken
+	}
+
+	if refreshToken.Rotated {
+		return jwtToken, s.revokeReusedFamily(ctx, refreshToken)
+	}
+
+	isRotated, err := s.refreshTokenRepository.MarkRotated(ctx, refreshToken.ID)
+	if err != nil {
+		return jwtToken, err
+	}
+
+	// lost the race against another request using the same token
+	if !isRotated {
+		return jwtToken, s.revokeReusedFamily(ctx, refreshToken)
+	}
 
-	salt := string(sha1.Sum(nil))[0:16]
-	block, err := aes.NewCipher([]byte(salt))
+	detailUser, err := s.userService.GetUserByID(ctx, refreshToken.UserID)
 	if err != nil {
-		log.Error(err.Error())
-		return token, err
+		return jwtToken, err
+	}
+
+	// the password changed or the user got suspended since this family was issued
+	if detailUser.Suspended || detailUser.TokenVersion != refreshToken.TokenVersion {
+		err = s.revokeSession(ctx, refreshToken.FamilyID)
+		if err != nil {
+			return jwtToken, err
+		}
+
+		return jwtToken, ErrInvalidRefreshToken
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	session := Session{}
+	session.ID = refreshToken.FamilyID
+	session.IPAddress = sessionInput.IPAddress
+	session.UserAgent = sessionInput.UserAgent
+	session.Device = deviceName(sessionInput.UserAgent)
+
+	err = s.refreshTokenRepository.TouchSession(ctx, session)
 	if err != nil {
-		return token, err
+		return jwtToken, err
+	}
+
+	jwtToken, err = s.generateAccessToken(detailUser, session.ID)
+	if err != nil {
+		return jwtToken, err
+	}
+
+	jwtToken.RefreshToken, err = s.issueRefreshToken(ctx, refreshToken.FamilyID, detailUser.ID, detailUser.TokenVersion)
+	if err != nil {
+		return jwtToken, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	_, err = io.ReadFull(rand.Reader, nonce)
+	return jwtToken, nil
+}
+
+// IsRevoked is checked on every request, it only looks at the in-memory denylist
+func (s *jwtService) IsRevoked(session key.Session) bool {
+	return s.denylist.Contains(session.TokenID) || s.denylist.Contains(session.ID)
+}
+
+// Logout ends the session of the given access token, the token itself stops working right away
+func (s *jwtService) Logout(session key.Session) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if session.TokenID != "" {
+		s.denylist.Add(session.TokenID, session.ExpiresAt.Add(s.config.ClockSkew))
+	}
+
+	// tokens issued before sessions existed have nothing else to revoke
+	if session.ID == "" {
+		return nil
+	}
+
+	return s.revokeSession(ctx, session.ID)
+}
+
+func (s *jwtService) GetSessions(userID string) ([]Session, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	return s.refreshTokenRepository.FindActiveSessionsByUserID(ctx, userID)
+}
+
+func (s *jwtService) GetSessionHistory(userID string) ([]Session, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	return s.refreshTokenRepository.FindSessionsByUserID(ctx, userID)
+}
+
+func (s *jwtService) RevokeSession(ID string, userID string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session, err := s.refreshTokenRepository.FindSessionByID(ctx, ID)
 	if err != nil {
-		return token, err
+		return err
+	}
+
+	// someone else's session looks the same as a missing one
+	if session.ID == "" || session.UserID != userID {
+		return ErrSessionNotFound
 	}
 
-	token.RefreshToken = base64.URLEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(token.AccessToken), nil))
-	return token, nil
+	return s.revokeSession(ctx, session.ID)
+}
+
+func (s *jwtService) JWKS() JWKS {
+	return s.keys.jwks()
 }
 
-func (s *jwtService) ValidateRefreshToken(token key.Token) (user.User, error) {
-	sha1 := sha1.New()
-	io.WriteString(sha1, os.Getenv("SECRET_KEY"))
+// Start loads the signing keys and keeps them and the denylist in sync with other instances
+func (s *jwtService) Start(ctx context.Context) {
+	s.keys.load(ctx)
+
+	if !s.keys.symmetric() {
+		go func() {
+			ticker := time.NewTicker(s.keys.config.RefreshInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					s.keys.load(ctx)
+				}
+			}
+		}()
+	}
 
-	user := user.User{}
-	// jwtToken := key.Token{}
+	since := time.Now().Add(-s.denylistTTL())
+	s.syncDenylist(ctx, since)
+
+	go func() {
+		ticker := time.NewTicker(s.denylistSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// overlap a little so a revoke committed during the last sync isn't missed
+				next := time.Now().Add(-time.Second)
+				s.syncDenylist(ctx, since)
+				s.denylist.Prune()
+				since = next
+			}
+		}
+	}()
+}
+
+func (s *jwtService) syncDenylist(ctx context.Context, since time.Time) {
+	sessionIDs, err := s.refreshTokenRepository.FindRevokedSessionIDsSince(ctx, since)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	until := time.Now().Add(s.denylistTTL())
+	for _, sessionID := range sessionIDs {
+		s.denylist.Add(sessionID, until)
+	}
+}
 
-	salt := string(sha1.Sum(nil))[0:16]
-	block, err := aes.NewCipher([]byte(salt))
+// revokeSession also denylists the session until every access token issued for it has expired
+func (s *jwtService) revokeSession(ctx context.Context, ID string) error {
+	err := s.refreshTokenRepository.RevokeSession(ctx, ID)
 	if err != nil {
-		return user, err
+		return err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	s.denylist.Add(ID, time.Now().Add(s.denylistTTL()))
+	return nil
+}
+
+// denylistTTL is how long an access token is still accepted after it was issued
+func (s *jwtService) denylistTTL() time.Duration {
+	return s.config.AccessTokenTTL + s.config.ClockSkew
+}
+
+func (s *jwtService) generateAccessToken(user user.User, sessionID string) (key.Token, error) {
+	now := time.Now()
+
+	claims := Claims{}
+	claims.Role = user.Role
+	claims.SessionID = sessionID
+	claims.TokenVersion = user.TokenVersion
+	claims.Subject = user.ID
+	claims.Issuer = s.config.Issuer
+	claims.Audience = jwt.ClaimStrings{s.config.Audience}
+	claims.ID = helper.GenerateID()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(s.config.AccessTokenTTL))
+
+	kid, signingKey, err := s.keys.sign()
 	if err != nil {
-		return user, err
+		return key.Token{}, err
+	}
+
+	token := jwt.NewWithClaims(s.keys.signingMethod(), claims)
+	if kid != "" {
+		token.Header["kid"] = kid
 	}
 
-	data, err := base64.URLEncoding.DecodeString(token.RefreshToken)
+	jwtToken := key.Token{}
+
+	jwtToken.AccessToken, err = token.SignedString(signingKey)
 	if err != nil {
-		return user, err
+		return jwtToken, err
 	}
 
-	nonceSize := gcm.NonceSize()
-	nonce, cipherText := data[:nonceSize], data[nonceSize:]
+	return jwtToken, nil
+}
+
+func (s *jwtService) issueRefreshToken(ctx context.Context, familyID string, userID string, tokenVersion int) (string, error) {
+	buf := make([]byte, 32)
 
-	plain, err := gcm.Open(nil, nonce, cipherText, nil)
+	_, err := rand.Read(buf)
 	if err != nil {
-		return user, err
+		return "", err
 	}
 
-	if string(plain) != token.AccessToken {
-		return user, errors.New("invalid token")
+	plainToken := base64.RawURLEncoding.EncodeToString(buf)
+
+	refreshToken := RefreshToken{}
+	refreshToken.ID = helper.GenerateID()
+	refreshToken.FamilyID = familyID
+	refreshToken.UserID = userID
+	refreshToken.TokenHash = hashRefreshToken(plainToken)
+	refreshToken.TokenVersion = tokenVersion
+	refreshToken.ExpiresAt = time.Now().Add(s.config.RefreshTokenTTL)
+
+	_, err = s.refreshTokenRepository.Save(ctx, refreshToken)
+	if err != nil {
+		return "", err
 	}
 
-	validatedToken, err := s.ValidateToken(string(plain))
+	return plainToken, nil
+}
+
+func (s *jwtService) revokeReusedFamily(ctx context.Context, refreshToken RefreshToken) error {
+	log.WithFields(log.Fields{
+		"user_id":   refreshToken.UserID,
+		"family_id": refreshToken.FamilyID,
+	}).Warn("refresh token reuse detected, revoking the family")
+
+	err := s.revokeSession(ctx, refreshToken.FamilyID)
 	if err != nil {
-		return user, err
+		return err
 	}
 
-	claim, ok := validatedToken.Claims.(jwt.MapClaims)
-	if !ok || !validatedToken.Valid {
-		return user, err
+	return ErrRefreshTokenReused
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// deviceName gives a short human readable name like "Chrome on Windows"
+func deviceName(userAgent string) string {
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
 	}
 
-	user.ID = fmt.Sprintf("%s", claim["user_id"])
+	systems := []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
 
-	return user, nil
+	browser := ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	system := ""
+	for _, o := range systems {
+		if strings.Contains(userAgent, o.token) {
+			system = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
 }
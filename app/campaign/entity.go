@@ -1,6 +1,15 @@
 package campaign
 
-import "time"
+import (
+	"funding-app/app/imaging"
+	"time"
+)
+
+const (
+	StatusActive      = "active"
+	StatusClosed      = "closed"
+	StatusUnpublished = "unpublished"
+)
 
 type (
 	Campaign struct {
@@ -14,6 +23,7 @@ type (
 		GoalAmount       int
 		CurrentAmount    int
 		BackerCount      int
+		Status           string
 		CreatedAt        time.Time
 		UpdatedAt        time.Time
 		CampaignImages   []CampaignImage
@@ -22,7 +32,9 @@ type (
 	CampaignImage struct {
 		ID         string
 		CampaignID string
+		MediaID    string
 		FileName   string
+		Variants   imaging.Variants
 		IsPrimary  int
 		CreatedAt  time.Time
 		UpdatedAt  time.Time
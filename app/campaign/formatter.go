@@ -1,14 +1,18 @@
 package campaign
 
+import "funding-app/app/imaging"
+
 type (
 	CampaignFormatter struct {
-		ID               string `json:"id"`
-		UserID           string `json:"user_id"`
-		Name             string `json:"name"`
-		ShortDescription string `json:"short_description"`
-		ImageURL         string `json:"image_url"`
-		CurrentAmount    int    `json:"current_amount"`
-		GoalAmount       int    `json:"goal_amount"`
+		ID               string           `json:"id"`
+		UserID           string           `json:"user_id"`
+		Name             string           `json:"name"`
+		ShortDescription string           `json:"short_description"`
+		ImageURL         string           `json:"image_url"`
+		ImageURLs        imaging.Variants `json:"image_urls"`
+		CurrentAmount    int              `json:"current_amount"`
+		GoalAmount       int              `json:"goal_amount"`
+		Status           string           `json:"status"`
 	}
 )
 
@@ -19,11 +23,14 @@ func FormatCampaign(campaign Campaign) CampaignFormatter {
 	formatter.Name = campaign.Name
 	formatter.ShortDescription = campaign.ShortDescription
 	formatter.ImageURL = ""
+	formatter.ImageURLs = imaging.Variants{}
 	formatter.CurrentAmount = campaign.CurrentAmount
 	formatter.GoalAmount = campaign.GoalAmount
+	formatter.Status = campaign.Status
 
 	if len(campaign.CampaignImages) > 0 {
 		formatter.ImageURL = campaign.CampaignImages[0].FileName
+		formatter.ImageURLs = campaign.CampaignImages[0].Variants
 	}
 
 	return formatter
@@ -17,4 +17,12 @@ type (
 		IsPrimary  bool   `form:"is_primary"`
 		User       user.User
 	}
+
+	SearchCampaignsInput struct {
+		Query   string
+		Status  string `validate:"omitempty,oneof=active closed unpublished"`
+		UserID  string
+		Page    int `validate:"min=1"`
+		PerPage int `validate:"min=1,max=100"`
+	}
 )
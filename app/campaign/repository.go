@@ -3,6 +3,7 @@ package campaign
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -11,12 +12,16 @@ import (
 type Repository interface {
 	FindAll(ctx context.Context) ([]Campaign, error)
 	FindByUserID(ctx context.Context, userID string) ([]Campaign, error)
+	FindAllByUserID(ctx context.Context, userID string) ([]Campaign, error)
 	FindByID(ctx context.Context, ID string) (Campaign, error)
 	Save(ctx context.Context, campaign Campaign) (Campaign, error)
 	FindImagesByCampaignID(ctx context.Context, campaignID string) ([]CampaignImage, error)
 	FindImagePrimaryByCampaignID(ctx context.Context, campaignID string) ([]CampaignImage, error)
 	SaveImage(ctx context.Context, campaignImage CampaignImage) (CampaignImage, error)
 	MarkAllImageAsNonPrimary(ctx context.Context, campaignID string) (bool, error)
+	DeleteImage(ctx context.Context, ID string, userID string) (CampaignImage, error)
+	Search(ctx context.Context, input SearchCampaignsInput) ([]Campaign, int, error)
+	UpdateStatus(ctx context.Context, ID string, status string) error
 }
 
 type repository struct {
@@ -34,7 +39,7 @@ func NewCampaignRepository(DB *sql.DB) Repository {
 func (r *repository) FindAll(ctx context.Context) ([]Campaign, error) {
 	campaigns := []Campaign{}
 
-	sqlQuery := "SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, created_at, updated_at FROM campaigns"
+	sqlQuery := "SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, status, created_at, updated_at FROM campaigns WHERE status <> 'unpublished'"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -63,6 +68,7 @@ func (r *repository) FindAll(ctx context.Context) ([]Campaign, error) {
 			&campaign.GoalAmount,
 			&campaign.CurrentAmount,
 			&campaign.BackerCount,
+			&campaign.Status,
 			&createdAt,
 			&updatedAt,
 		)
@@ -96,7 +102,7 @@ func (r *repository) FindAll(ctx context.Context) ([]Campaign, error) {
 func (r *repository) FindByUserID(ctx context.Context, userID string) ([]Campaign, error) {
 	campaigns := []Campaign{}
 
-	sqlQuery := "SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, created_at, updated_at FROM campaigns WHERE user_id = $1"
+	sqlQuery := "SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, status, created_at, updated_at FROM campaigns WHERE user_id = $1 AND status <> 'unpublished'"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -125,6 +131,7 @@ func (r *repository) FindByUserID(ctx context.Context, userID string) ([]Campaig
 			&campaign.GoalAmount,
 			&campaign.CurrentAmount,
 			&campaign.BackerCount,
+			&campaign.Status,
 			&createdAt,
 			&updatedAt,
 		)
@@ -149,11 +156,68 @@ func (r *repository) FindByUserID(ctx context.Context, userID string) ([]Campaig
 	return campaigns, nil
 }
 
+// FindAllByUserID includes unpublished campaigns, it is meant for the owner and not for public listings
+func (r *repository) FindAllByUserID(ctx context.Context, userID string) ([]Campaign, error) {
+	campaigns := []Campaign{}
+
+	sqlQuery := "SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, status, created_at, updated_at FROM campaigns WHERE user_id = $1 ORDER BY created_at"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return campaigns, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return campaigns, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		campaign := Campaign{}
+		var createdAt, updatedAt string
+
+		err := rows.Scan(
+			&campaign.ID,
+			&campaign.UserID,
+			&campaign.Name,
+			&campaign.ShortDescription,
+			&campaign.Description,
+			&campaign.Slug,
+			&campaign.Perks,
+			&campaign.GoalAmount,
+			&campaign.CurrentAmount,
+			&campaign.BackerCount,
+			&campaign.Status,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return campaigns, err
+		}
+
+		if campaign.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if campaign.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
+		}
+
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, nil
+}
+
 func (r *repository) FindByID(ctx context.Context, ID string) (Campaign, error) {
 	campaign := Campaign{}
 	var createdAt, updatedAt string
 
-	sqlQuery := "SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, created_at, updated_at FROM campaigns WHERE id = $1"
+	sqlQuery := "SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, status, created_at, updated_at FROM campaigns WHERE id = $1"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -179,6 +243,7 @@ func (r *repository) FindByID(ctx context.Context, ID string) (Campaign, error)
 			&campaign.GoalAmount,
 			&campaign.CurrentAmount,
 			&campaign.BackerCount,
+			&campaign.Status,
 			&createdAt,
 			&updatedAt,
 		)
@@ -202,7 +267,7 @@ func (r *repository) FindByID(ctx context.Context, ID string) (Campaign, error)
 }
 
 func (r *repository) Save(ctx context.Context, campaign Campaign) (Campaign, error) {
-	sqlQuery := "INSERT into campaigns (id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)"
+	sqlQuery := "INSERT into campaigns (id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, status, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -221,6 +286,7 @@ func (r *repository) Save(ctx context.Context, campaign Campaign) (Campaign, err
 		&campaign.GoalAmount,
 		&campaign.CurrentAmount,
 		&campaign.BackerCount,
+		&campaign.Status,
 		time.Now().Format(layoutDateTime),
 		time.Now().Format(layoutDateTime),
 	)
@@ -236,7 +302,7 @@ func (r *repository) Save(ctx context.Context, campaign Campaign) (Campaign, err
 func (r *repository) FindImagesByCampaignID(ctx context.Context, campaignID string) ([]CampaignImage, error) {
 	campaignImages := []CampaignImage{}
 
-	sqlQuery := "SELECT id, campaign_id, file_name, is_primary FROM campaign_images WHERE id = $1"
+	sqlQuery := "SELECT id, campaign_id, COALESCE(media_id, ''), file_name, variants, is_primary FROM campaign_images WHERE id = $1"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -256,7 +322,9 @@ func (r *repository) FindImagesByCampaignID(ctx context.Context, campaignID stri
 		err := rows.Scan(
 			&campaignImage.ID,
 			&campaignImage.CampaignID,
+			&campaignImage.MediaID,
 			&campaignImage.FileName,
+			&campaignImage.Variants,
 			&campaignImage.IsPrimary,
 		)
 
@@ -273,7 +341,7 @@ func (r *repository) FindImagesByCampaignID(ctx context.Context, campaignID stri
 func (r *repository) FindImagePrimaryByCampaignID(ctx context.Context, campaignID string) ([]CampaignImage, error) {
 	campaignImages := []CampaignImage{}
 
-	sqlQuery := "SELECT id, campaign_id, file_name, is_primary FROM campaign_images WHERE campaign_id = $1 AND is_primary = 1"
+	sqlQuery := "SELECT id, campaign_id, COALESCE(media_id, ''), file_name, variants, is_primary FROM campaign_images WHERE campaign_id = $1 AND is_primary = 1"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -293,7 +361,9 @@ func (r *repository) FindImagePrimaryByCampaignID(ctx context.Context, campaignI
 		err := rows.Scan(
 			&campaignImage.ID,
 			&campaignImage.CampaignID,
+			&campaignImage.MediaID,
 			&campaignImage.FileName,
+			&campaignImage.Variants,
 			&campaignImage.IsPrimary,
 		)
 
@@ -309,7 +379,7 @@ func (r *repository) FindImagePrimaryByCampaignID(ctx context.Context, campaignI
 }
 
 func (r *repository) SaveImage(ctx context.Context, campaignImage CampaignImage) (CampaignImage, error) {
-	sqlQuery := "INSERT INTO campaign_images (id, campaign_id, file_name, is_primary, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6)"
+	sqlQuery := "INSERT INTO campaign_images (id, campaign_id, media_id, file_name, variants, is_primary, created_at, updated_at) VALUES($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -321,7 +391,9 @@ func (r *repository) SaveImage(ctx context.Context, campaignImage CampaignImage)
 	_, err = stmt.ExecContext(ctx,
 		&campaignImage.ID,
 		&campaignImage.CampaignID,
+		&campaignImage.MediaID,
 		&campaignImage.FileName,
+		campaignImage.Variants,
 		&campaignImage.IsPrimary,
 		time.Now().Format(layoutDateTime),
 		time.Now().Format(layoutDateTime),
@@ -352,3 +424,137 @@ func (r *repository) MarkAllImageAsNonPrimary(ctx context.Context, campaignID st
 
 	return true, nil
 }
+
+// DeleteImage removes an image of a campaign owned by the user, the newest remaining image
+// becomes primary when the primary one is removed
+func (r *repository) DeleteImage(ctx context.Context, ID string, userID string) (CampaignImage, error) {
+	campaignImage := CampaignImage{}
+
+	sqlQuery := `WITH deleted AS (
+			DELETE FROM campaign_images ci USING campaigns c
+			WHERE ci.id = $1 AND ci.campaign_id = c.id AND c.user_id = $2
+			RETURNING ci.id, ci.campaign_id, ci.media_id, ci.file_name, ci.variants, ci.is_primary
+		), promoted AS (
+			UPDATE campaign_images SET is_primary = 1, updated_at = $3
+			WHERE id = (
+				SELECT ci.id FROM campaign_images ci, deleted d
+				WHERE ci.campaign_id = d.campaign_id AND ci.id <> d.id AND d.is_primary = 1
+				ORDER BY ci.created_at DESC LIMIT 1
+			)
+		)
+		SELECT id, campaign_id, COALESCE(media_id, ''), file_name, variants, is_primary FROM deleted`
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return campaignImage, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, ID, userID, time.Now().Format(layoutDateTime)).Scan(
+		&campaignImage.ID,
+		&campaignImage.CampaignID,
+		&campaignImage.MediaID,
+		&campaignImage.FileName,
+		&campaignImage.Variants,
+		&campaignImage.IsPrimary,
+	)
+
+	if err != nil {
+		return campaignImage, err
+	}
+
+	return campaignImage, nil
+}
+
+// Search returns one page of campaigns in any status together with the total number of matches
+func (r *repository) Search(ctx context.Context, input SearchCampaignsInput) ([]Campaign, int, error) {
+	campaigns := []Campaign{}
+	total := 0
+
+	sqlQuery := `SELECT id, user_id, name, short_description, description, slug, perks, goal_amount, current_amount, backer_count, status, created_at, updated_at, COUNT(*) OVER()
+		FROM campaigns
+		WHERE ($1 = '' OR name ILIKE '%' || $1 || '%' OR slug ILIKE '%' || $1 || '%')
+			AND ($2 = '' OR status = $2)
+			AND ($3 = '' OR user_id = $3)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5`
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return campaigns, total, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, input.Query, input.Status, input.UserID, input.PerPage, (input.Page-1)*input.PerPage)
+	if err != nil {
+		return campaigns, total, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		campaign := Campaign{}
+		var createdAt, updatedAt string
+
+		err := rows.Scan(
+			&campaign.ID,
+			&campaign.UserID,
+			&campaign.Name,
+			&campaign.ShortDescription,
+			&campaign.Description,
+			&campaign.Slug,
+			&campaign.Perks,
+			&campaign.GoalAmount,
+			&campaign.CurrentAmount,
+			&campaign.BackerCount,
+			&campaign.Status,
+			&createdAt,
+			&updatedAt,
+			&total,
+		)
+		if err != nil {
+			return campaigns, total, err
+		}
+
+		if campaign.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if campaign.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
+		}
+
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, total, nil
+}
+
+func (r *repository) UpdateStatus(ctx context.Context, ID string, status string) error {
+	sqlQuery := "UPDATE campaigns SET status = $1, updated_at = $2 WHERE id = $3"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, status, time.Now().Format(layoutDateTime), ID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if int(affected) == 0 {
+		return errors.New("failed when update")
+	}
+
+	return nil
+}
@@ -2,33 +2,44 @@ package campaign
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"funding-app/app/helper"
 	"funding-app/app/key"
+	"funding-app/app/media"
 	"mime/multipart"
 	"strings"
 	"sync"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type Service interface {
-	GetCampaigns(userID string) ([]Campaign, error)
-	GetCampaignDetail(ID string) (Campaign, error)
-	CreateCampaign(input CreateCampaignInput) (Campaign, error)
-	UploadCampaignImage(input CreateCampaignImageInput, uploadedFile multipart.File) (CampaignImage, error)
+	GetCampaigns(ctx context.Context, userID string) ([]Campaign, error)
+	GetCampaignDetail(ctx context.Context, ID string) (Campaign, error)
+	GetOwnedCampaigns(ctx context.Context, userID string) ([]Campaign, error)
+	CreateCampaign(ctx context.Context, input CreateCampaignInput) (Campaign, error)
+	UploadCampaignImage(ctx context.Context, input CreateCampaignImageInput, uploadedFile multipart.File) (CampaignImage, error)
+	DeleteCampaignImage(ctx context.Context, ID string, userID string) error
+	SearchCampaigns(ctx context.Context, input SearchCampaignsInput) ([]Campaign, int, error)
+	ChangeStatus(ctx context.Context, ID string, status string) (Campaign, error)
 }
 
+var (
+	ErrCampaignNotFound = errors.New("no campaign found")
+	ErrImageNotFound    = errors.New("no campaign image found")
+)
+
 type service struct {
 	campaignRepository Repository
+	mediaService       media.Service
 }
 
-func NewCampaignService(campaignRepository Repository) Service {
-	return &service{campaignRepository}
+func NewCampaignService(campaignRepository Repository, mediaService media.Service) Service {
+	return &service{campaignRepository, mediaService}
 }
 
-func (s *service) GetCampaigns(userID string) ([]Campaign, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
+func (s *service) GetCampaigns(ctx context.Context, userID string) ([]Campaign, error) {
 	if userID != "" {
 		campaigns, err := s.campaignRepository.FindByUserID(ctx, userID)
 		if err != nil {
@@ -46,27 +57,39 @@ func (s *service) GetCampaigns(userID string) ([]Campaign, error) {
 	return campaigns, nil
 }
 
-func (s *service) GetCampaignDetail(ID string) (Campaign, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
+func (s *service) GetCampaignDetail(ctx context.Context, ID string) (Campaign, error) {
 	campaign, err := s.campaignRepository.FindByID(ctx, ID)
 	if err != nil {
 		return campaign, err
 	}
 
-	if campaign.ID == "" {
-		return campaign, errors.New("no campaign found")
+	// unpublished campaigns are only visible through the admin API
+	if campaign.ID == "" || campaign.Status == StatusUnpublished {
+		return Campaign{}, ErrCampaignNotFound
 	}
 
 	return campaign, nil
 }
 
-func (s *service) CreateCampaign(input CreateCampaignInput) (Campaign, error) {
-	var campaign Campaign
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// GetOwnedCampaigns returns every campaign of the user with its images, unpublished ones included
+func (s *service) GetOwnedCampaigns(ctx context.Context, userID string) ([]Campaign, error) {
+	campaigns, err := s.campaignRepository.FindAllByUserID(ctx, userID)
+	if err != nil {
+		return campaigns, err
+	}
+
+	for i, campaign := range campaigns {
+		campaigns[i].CampaignImages, err = s.campaignRepository.FindImagesByCampaignID(ctx, campaign.ID)
+		if err != nil {
+			return campaigns, err
+		}
+	}
+
+	return campaigns, nil
+}
 
+func (s *service) CreateCampaign(ctx context.Context, input CreateCampaignInput) (Campaign, error) {
+	var campaign Campaign
 	campaign.ID = helper.GenerateID()
 	campaign.UserID = input.User.ID
 	campaign.Name = input.Name
@@ -74,6 +97,7 @@ func (s *service) CreateCampaign(input CreateCampaignInput) (Campaign, error) {
 	campaign.Description = input.Description
 	campaign.Perks = input.Perks
 	campaign.GoalAmount = input.GoalAmount
+	campaign.Status = StatusActive
 
 	slugCandidate := strings.Join(strings.Split(strings.ToLower(campaign.Name), " "), "-")
 	campaign.Slug = slugCandidate
@@ -86,13 +110,10 @@ func (s *service) CreateCampaign(input CreateCampaignInput) (Campaign, error) {
 	return newCampaign, nil
 }
 
-func (s *service) UploadCampaignImage(input CreateCampaignImageInput, uploadedFile multipart.File) (CampaignImage, error) {
+func (s *service) UploadCampaignImage(ctx context.Context, input CreateCampaignImageInput, uploadedFile multipart.File) (CampaignImage, error) {
 	var wg sync.WaitGroup
 	campaignImage := CampaignImage{}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	ch := make(chan key.FileUploadResponse)
 	defer close(ch)
 
@@ -122,7 +143,7 @@ func (s *service) UploadCampaignImage(input CreateCampaignImageInput, uploadedFi
 	wg.Add(1)
 
 	// make goroutine with passing channel
-	go helper.ImageUploadCampaignImageHandler(&wg, uploadedFile, ch)
+	go media.ImageUploadCampaignImageHandler(ctx, &wg, s.mediaService, uploadedFile, ch)
 	fileResponse := <-ch
 
 	wg.Wait()
@@ -131,11 +152,58 @@ func (s *service) UploadCampaignImage(input CreateCampaignImageInput, uploadedFi
 		return campaignImage, fileResponse.Err
 	}
 
+	campaignImage.MediaID = fileResponse.MediaID
 	campaignImage.FileName = fileResponse.SecureURL
+	campaignImage.Variants = fileResponse.Variants
 	newCampaignImage, err := s.campaignRepository.SaveImage(ctx, campaignImage)
 	if err != nil {
+		s.mediaService.Release(ctx, fileResponse.MediaID)
 		return newCampaignImage, err
 	}
 
 	return newCampaignImage, nil
 }
+
+func (s *service) DeleteCampaignImage(ctx context.Context, ID string, userID string) error {
+	// images of other users' campaigns are reported as missing
+	campaignImage, err := s.campaignRepository.DeleteImage(ctx, ID, userID)
+	if err == sql.ErrNoRows {
+		return ErrImageNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// the removed image loses its reference
+	err = s.mediaService.Release(ctx, campaignImage.MediaID)
+	if err != nil {
+		log.Error(err)
+	}
+
+	return nil
+}
+
+func (s *service) SearchCampaigns(ctx context.Context, input SearchCampaignsInput) ([]Campaign, int, error) {
+	return s.campaignRepository.Search(ctx, input)
+}
+
+// ChangeStatus force-closes, unpublishes or reopens a campaign
+func (s *service) ChangeStatus(ctx context.Context, ID string, status string) (Campaign, error) {
+	campaign, err := s.campaignRepository.FindByID(ctx, ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == "" {
+		return campaign, ErrCampaignNotFound
+	}
+
+	err = s.campaignRepository.UpdateStatus(ctx, ID, status)
+	if err != nil {
+		return campaign, err
+	}
+
+	campaign.Status = status
+	return campaign, nil
+}
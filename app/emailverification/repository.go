@@ -0,0 +1,59 @@
+package emailverification
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Repository interface {
+	Save(ctx context.Context, ID string, userID string, email string) error
+	CountSince(ctx context.Context, userID string, since time.Time) (int, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewEmailVerificationRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, ID string, userID string, email string) error {
+	sqlQuery := "INSERT INTO email_verifications (id, user_id, email, sent_at) VALUES($1, $2, $3, $4)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, ID, userID, email, time.Now().Format(layoutDateTime))
+	return err
+}
+
+// CountSince returns how many verification emails were sent to the user after the given time
+func (r *repository) CountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	var count int
+
+	sqlQuery := "SELECT COUNT(*) FROM email_verifications WHERE user_id = $1 AND sent_at > $2"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return count, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, userID, since.Format(layoutDateTime)).Scan(&count)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
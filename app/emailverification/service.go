@@ -0,0 +1,191 @@
+package emailverification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"funding-app/app/helper"
+	"funding-app/app/mailer"
+	"funding-app/app/user"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken    = errors.New("verification link is invalid or has expired")
+	ErrAlreadyVerified = errors.New("email is already verified")
+	ErrTooManyRequests = errors.New("verification email was sent recently, please wait before asking again")
+	ErrMissingSecret   = errors.New("EMAIL_VERIFICATION_SECRET or SECRET_KEY must be set")
+)
+
+type Service interface {
+	SendVerification(user user.User) error
+	ResendVerification(userID string) error
+	VerifyEmail(token string) (user.User, error)
+}
+
+type service struct {
+	emailVerificationRepository Repository
+	userService                 user.Service
+	mailer                      mailer.Mailer
+	secret                      []byte
+	tokenTTL                    time.Duration
+	verifyURL                   string
+	resendInterval              time.Duration
+	maxPerHour                  int
+}
+
+func NewEmailVerificationService(emailVerificationRepository Repository, userService user.Service, mailer mailer.Mailer) (Service, error) {
+	secret := helper.GetEnv("EMAIL_VERIFICATION_SECRET", os.Getenv("SECRET_KEY"))
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	return &service{
+		emailVerificationRepository: emailVerificationRepository,
+		userService:                 userService,
+		mailer:                      mailer,
+		secret:                      []byte(secret),
+		tokenTTL:                    helper.GetEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		verifyURL:                   helper.GetEnv("EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email"),
+		resendInterval:              helper.GetEnvDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+		maxPerHour:                  helper.GetEnvInt("EMAIL_VERIFICATION_MAX_PER_HOUR", 5),
+	}, nil
+}
+
+func (s *service) SendVerification(user user.User) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	err := s.emailVerificationRepository.Save(ctx, helper.GenerateID(), user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	token := s.sign(user.ID, user.Email, time.Now().Add(s.tokenTTL))
+	s.mailer.SendAsync(mailer.Mail{
+		To:       user.Email,
+		Template: mailer.TemplateEmailVerification,
+		Data: map[string]interface{}{
+			"Name":      user.Name,
+			"URL":       s.verifyURL + "?token=" + token,
+			"ExpiresIn": s.tokenTTL.String(),
+		},
+	})
+
+	return nil
+}
+
+func (s *service) ResendVerification(userID string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	currentUser, err := s.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if currentUser.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	now := time.Now()
+
+	recent, err := s.emailVerificationRepository.CountSince(ctx, userID, now.Add(-s.resendInterval))
+	if err != nil {
+		return err
+	}
+
+	lastHour, err := s.emailVerificationRepository.CountSince(ctx, userID, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if recent > 0 || lastHour >= s.maxPerHour {
+		return ErrTooManyRequests
+	}
+
+	return s.SendVerification(currentUser)
+}
+
+func (s *service) VerifyEmail(token string) (user.User, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	userID, email, err := s.verify(token)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	verifiedUser, err := s.userService.VerifyEmail(ctx, userID, email)
+	if err == user.ErrEmailChanged {
+		return verifiedUser, ErrInvalidToken
+	}
+
+	return verifiedUser, err
+}
+
+// tokenPayload is json encoded so no email, whatever characters it holds, can be read as another field
+type tokenPayload struct {
+	UserID    string `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// the token carries user id, email and expiry so no state is needed to check it,
+// changing the email makes old links useless
+func (s *service) sign(userID string, email string, expiresAt time.Time) string {
+	payload, _ := json.Marshal(tokenPayload{
+		UserID:    userID,
+		Email:     strings.ToLower(email),
+		ExpiresAt: expiresAt.Unix(),
+	})
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) verify(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", "", ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", "", ErrInvalidToken
+	}
+
+	fields := tokenPayload{}
+	err = json.Unmarshal(payload, &fields)
+	if err != nil || fields.UserID == "" || fields.Email == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	if time.Now().Unix() > fields.ExpiresAt {
+		return "", "", ErrInvalidToken
+	}
+
+	return fields.UserID, fields.Email, nil
+}
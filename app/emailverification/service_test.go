@@ -0,0 +1,65 @@
+package emailverification
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	s := &service{secret: []byte("test-secret")}
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{"plain", "jane@example.com"},
+		{"upper case", "Jane@Example.com"},
+		{"separator in local part", "jane|doe@example.com"},
+		{"quoted local part", "\"jane.doe\"@example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := s.sign("user-1", test.email, time.Now().Add(time.Hour))
+
+			userID, email, err := s.verify(token)
+			if err != nil {
+				t.Fatalf("verify = %v", err)
+			}
+
+			if userID != "user-1" {
+				t.Errorf("user id = %q, want %q", userID, "user-1")
+			}
+
+			if email != strings.ToLower(test.email) {
+				t.Errorf("email = %q, want %q", email, strings.ToLower(test.email))
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsBadTokens(t *testing.T) {
+	s := &service{secret: []byte("test-secret")}
+	other := &service{secret: []byte("other-secret")}
+	valid := s.sign("user-1", "jane@example.com", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", s.sign("user-1", "jane@example.com", time.Now().Add(-time.Minute))},
+		{"other secret", other.sign("user-1", "jane@example.com", time.Now().Add(time.Hour))},
+		{"tampered payload", "x" + valid},
+		{"missing signature", valid[:strings.Index(valid, ".")]},
+		{"empty", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := s.verify(test.token); err != ErrInvalidToken {
+				t.Errorf("verify = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/json"
+	"funding-app/app/account"
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/user"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type accountHandler struct {
+	accountService account.Service
+}
+
+func NewAccountHandler(accountService account.Service) *accountHandler {
+	return &accountHandler{accountService}
+}
+
+func (h *accountHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	job, err := h.accountService.RequestExport(currentUser.ID)
+	if err != nil {
+		if err == account.ErrQueueFull {
+			response := helper.APIResponse("Failed to request data export", http.StatusServiceUnavailable, "error", err.Error())
+			helper.JSON(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		response := helper.APIResponse("Failed to request data export", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := account.FormatJob(job)
+	response := helper.APIResponse("Data export is being prepared, you will get an email when it is ready", http.StatusAccepted, "success", formatter)
+	helper.JSON(w, response, http.StatusAccepted)
+}
+
+func (h *accountHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	job, err := h.accountService.GetJob(jobID, currentUser.ID)
+	if err != nil || job.Kind != account.KindExport {
+		response := helper.APIResponse("Failed to get data export", http.StatusNotFound, "error", account.ErrJobNotFound.Error())
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	formatter := account.FormatJob(job)
+	response := helper.APIResponse("Data export status", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *accountHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	archive, err := h.accountService.OpenArchive(jobID, currentUser.ID)
+	if err != nil {
+		if err == account.ErrJobNotFound || err == account.ErrArchiveNotReady {
+			response := helper.APIResponse("Failed to download data export", http.StatusNotFound, "error", err.Error())
+			helper.JSON(w, response, http.StatusNotFound)
+			return
+		}
+
+		response := helper.APIResponse("Failed to download data export", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		response := helper.APIResponse("Failed to download data export", http.StatusInternalServerError, "error", err.Error())
+		helper.JSON(w, response, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="funding-app-export-`+jobID+`.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), archive)
+}
+
+func (h *accountHandler) RequestDeletionLink(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	err := h.accountService.RequestDeletionLink(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to send deletion link", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	response := helper.APIResponse("A link to confirm the deletion has been sent to your email", http.StatusAccepted, "success", nil)
+	helper.JSON(w, response, http.StatusAccepted)
+}
+
+func (h *accountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to delete account", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	v := validator.New()
+	input := account.DeleteAccountInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to delete account", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to delete account", err)
+		return
+	}
+
+	job, err := h.accountService.RequestDeletion(currentUser.ID, input)
+	if err != nil {
+		switch err {
+		case user.ErrWrongPassword, account.ErrInvalidToken:
+			response := helper.APIResponse("Failed to delete account", http.StatusForbidden, "error", err.Error())
+			helper.JSON(w, response, http.StatusForbidden)
+		case account.ErrLiveCampaigns:
+			response := helper.APIResponse("Failed to delete account", http.StatusConflict, "error", err.Error())
+			helper.JSON(w, response, http.StatusConflict)
+		case account.ErrQueueFull:
+			response := helper.APIResponse("Failed to delete account", http.StatusServiceUnavailable, "error", err.Error())
+			helper.JSON(w, response, http.StatusServiceUnavailable)
+		default:
+			response := helper.APIResponse("Failed to delete account", http.StatusBadRequest, "error", err.Error())
+			helper.JSON(w, response, http.StatusBadRequest)
+		}
+		return
+	}
+
+	formatter := account.FormatJob(job)
+	response := helper.APIResponse("Account deletion has started, you will get an email when it is done", http.StatusAccepted, "success", formatter)
+	helper.JSON(w, response, http.StatusAccepted)
+}
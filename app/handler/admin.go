@@ -0,0 +1,388 @@
+package handler
+
+import (
+	"encoding/json"
+	"funding-app/app/audit"
+	"funding-app/app/campaign"
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/passwordpolicy"
+	"funding-app/app/transaction"
+	"funding-app/app/user"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type adminHandler struct {
+	userService        user.Service
+	campaignService    campaign.Service
+	transactionService transaction.Service
+	auditService       audit.Service
+}
+
+func NewAdminHandler(userService user.Service, campaignService campaign.Service, transactionService transaction.Service, auditService audit.Service) *adminHandler {
+	return &adminHandler{userService, campaignService, transactionService, auditService}
+}
+
+func (h *adminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	input := user.SearchUsersInput{}
+	input.Query = r.URL.Query().Get("q")
+	input.Role = r.URL.Query().Get("role")
+	input.Suspended = r.URL.Query().Get("suspended")
+	input.Page, input.PerPage = helper.ParsePage(r)
+
+	// validate input
+	err := v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to search users", err)
+		return
+	}
+
+	users, total, err := h.userService.SearchUsers(r.Context(), input)
+	if err != nil {
+		response := helper.APIResponse("Failed to search users", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := helper.PaginatedFormatter{
+		Items:      user.FormatAdminUsers(users),
+		Pagination: helper.NewPagination(input.Page, input.PerPage, total),
+	}
+
+	response := helper.APIResponse("List of users", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	detailUser, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get user", http.StatusNotFound, "error", err.Error())
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	formatter := user.FormatAdminUser(detailUser)
+	response := helper.APIResponse("Detail of user", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to suspend user", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := user.SuspendUserInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to suspend user", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to suspend user", err)
+		return
+	}
+
+	// admins can't lock themselves out
+	if userID == currentAdmin(r).ID {
+		response := helper.APIResponse("Failed to suspend user", http.StatusBadRequest, "error", "you can't suspend yourself")
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	updatedUser, err := h.userService.SuspendUser(r.Context(), userID, input)
+	if err != nil {
+		response := helper.APIResponse("Failed to suspend user", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	h.record(r, audit.ActionUserSuspend, audit.TargetUser, updatedUser.ID, audit.Metadata{"reason": updatedUser.SuspendReason})
+
+	formatter := user.FormatAdminUser(updatedUser)
+	response := helper.APIResponse("User has been suspended", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	updatedUser, err := h.userService.UnsuspendUser(r.Context(), userID)
+	if err != nil {
+		response := helper.APIResponse("Failed to unsuspend user", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	h.record(r, audit.ActionUserUnsuspend, audit.TargetUser, updatedUser.ID, nil)
+
+	formatter := user.FormatAdminUser(updatedUser)
+	response := helper.APIResponse("User has been unsuspended", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	updatedUser, err := h.userService.UnlockUser(r.Context(), userID)
+	if err != nil {
+		response := helper.APIResponse("Failed to unlock user", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	h.record(r, audit.ActionUserUnlock, audit.TargetUser, updatedUser.ID, nil)
+
+	formatter := user.FormatAdminUser(updatedUser)
+	response := helper.APIResponse("User has been unlocked", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to change role", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := user.ChangeRoleInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to change role", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to change role", err)
+		return
+	}
+
+	// admins can't demote themselves, another admin has to do it
+	if userID == currentAdmin(r).ID {
+		response := helper.APIResponse("Failed to change role", http.StatusBadRequest, "error", "you can't change your own role")
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	detailUser, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		response := helper.APIResponse("Failed to change role", http.StatusNotFound, "error", err.Error())
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	updatedUser, err := h.userService.ChangeRole(r.Context(), userID, input)
+	if err != nil {
+		response := helper.APIResponse("Failed to change role", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	h.record(r, audit.ActionUserRoleChange, audit.TargetUser, updatedUser.ID, audit.Metadata{"from": detailUser.Role, "to": updatedUser.Role})
+
+	formatter := user.FormatAdminUser(updatedUser)
+	response := helper.APIResponse("Role has been changed", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) GetUserTransactions(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	input := transaction.GetUserTransactionsInput{}
+	input.UserID = chi.URLParam(r, "id")
+	input.Page, input.PerPage = helper.ParsePage(r)
+
+	// validate input
+	err := v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to get transactions", err)
+		return
+	}
+
+	transactions, total, err := h.transactionService.GetUserTransactions(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get transactions", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// payment details of another user are sensitive, reading them is audited too
+	h.record(r, audit.ActionUserTransactionsView, audit.TargetUser, input.UserID, audit.Metadata{"page": input.Page})
+
+	formatter := helper.PaginatedFormatter{
+		Items:      transaction.FormatTransactions(transactions),
+		Pagination: helper.NewPagination(input.Page, input.PerPage, total),
+	}
+
+	response := helper.APIResponse("List of transactions", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) SearchCampaigns(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	input := campaign.SearchCampaignsInput{}
+	input.Query = r.URL.Query().Get("q")
+	input.Status = r.URL.Query().Get("status")
+	input.UserID = r.URL.Query().Get("user_id")
+	input.Page, input.PerPage = helper.ParsePage(r)
+
+	// validate input
+	err := v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to search campaigns", err)
+		return
+	}
+
+	campaigns, total, err := h.campaignService.SearchCampaigns(r.Context(), input)
+	if err != nil {
+		response := helper.APIResponse("Failed to search campaigns", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := helper.PaginatedFormatter{
+		Items:      campaign.FormatCampaigns(campaigns),
+		Pagination: helper.NewPagination(input.Page, input.PerPage, total),
+	}
+
+	response := helper.APIResponse("List of campaigns", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) CloseCampaign(w http.ResponseWriter, r *http.Request) {
+	h.changeCampaignStatus(w, r, campaign.StatusClosed, audit.ActionCampaignClose)
+}
+
+func (h *adminHandler) UnpublishCampaign(w http.ResponseWriter, r *http.Request) {
+	h.changeCampaignStatus(w, r, campaign.StatusUnpublished, audit.ActionCampaignUnpublish)
+}
+
+func (h *adminHandler) PublishCampaign(w http.ResponseWriter, r *http.Request) {
+	h.changeCampaignStatus(w, r, campaign.StatusActive, audit.ActionCampaignPublish)
+}
+
+func (h *adminHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	input := audit.GetLogsInput{}
+	input.ActorID = r.URL.Query().Get("actor_id")
+	input.TargetID = r.URL.Query().Get("target_id")
+	input.Action = r.URL.Query().Get("action")
+	input.Page, input.PerPage = helper.ParsePage(r)
+
+	// validate input
+	err := v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to get audit logs", err)
+		return
+	}
+
+	logs, total, err := h.auditService.GetLogs(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get audit logs", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := helper.PaginatedFormatter{
+		Items:      audit.FormatLogs(logs),
+		Pagination: helper.NewPagination(input.Page, input.PerPage, total),
+	}
+
+	response := helper.APIResponse("List of audit logs", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *adminHandler) changeCampaignStatus(w http.ResponseWriter, r *http.Request, status string, action string) {
+	campaignID := chi.URLParam(r, "id")
+
+	updatedCampaign, err := h.campaignService.ChangeStatus(r.Context(), campaignID, status)
+	if err != nil {
+		if err == campaign.ErrCampaignNotFound {
+			response := helper.APIResponse("Failed to change campaign status", http.StatusNotFound, "error", err.Error())
+			helper.JSON(w, response, http.StatusNotFound)
+			return
+		}
+
+		response := helper.APIResponse("Failed to change campaign status", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	h.record(r, action, audit.TargetCampaign, updatedCampaign.ID, nil)
+
+	formatter := campaign.FormatCampaign(updatedCampaign)
+	response := helper.APIResponse("Campaign status has been changed", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+// record writes the audit entry for an action the current admin has already performed
+func (h *adminHandler) record(r *http.Request, action string, targetType string, targetID string, metadata audit.Metadata) {
+	input := audit.RecordInput{}
+	input.ActorID = currentAdmin(r).ID
+	input.Action = action
+	input.TargetType = targetType
+	input.TargetID = targetID
+	input.Metadata = metadata
+	input.IPAddress = clientIP(r)
+
+	// Record logs the failure itself, the action already happened so the response stays successful
+	h.auditService.Record(input)
+}
+
+func currentAdmin(r *http.Request) user.User {
+	// get user data from middleware
+	return r.Context().Value(key.CtxAuthKey{}).(user.User)
+}
+
+func respondValidationError(w http.ResponseWriter, message string, err error) {
+	var errors []string
+
+	for _, e := range err.(validator.ValidationErrors) {
+		errors = append(errors, e.Error())
+	}
+
+	response := helper.APIResponse(message, http.StatusUnprocessableEntity, "error", errors)
+	helper.JSON(w, response, http.StatusUnprocessableEntity)
+}
+
+// respondPasswordPolicyError answers with every rule the password broke, it reports false for any other error
+func respondPasswordPolicyError(w http.ResponseWriter, message string, field string, err error) bool {
+	policyErr, ok := err.(*passwordpolicy.Error)
+	if !ok {
+		return false
+	}
+
+	formatter := passwordpolicy.FormatViolations(field, policyErr)
+	response := helper.APIResponse(message, http.StatusUnprocessableEntity, "error", formatter)
+	helper.JSON(w, response, http.StatusUnprocessableEntity)
+	return true
+}
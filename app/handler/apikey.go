@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"funding-app/app/apikey"
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/user"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type apiKeyHandler struct {
+	apiKeyService apikey.Service
+}
+
+func NewAPIKeyHandler(apiKeyService apikey.Service) *apiKeyHandler {
+	return &apiKeyHandler{apiKeyService}
+}
+
+func (h *apiKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to create API key", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	v := validator.New()
+	input := apikey.CreateAPIKeyInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to create API key", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to create API key", err)
+		return
+	}
+
+	newAPIKey, plaintext, err := h.apiKeyService.CreateAPIKey(currentUser, input)
+	if err != nil {
+		if err == apikey.ErrScopeNotAllowed {
+			response := helper.APIResponse("Failed to create API key", http.StatusForbidden, "error", err.Error())
+			helper.JSON(w, response, http.StatusForbidden)
+			return
+		}
+
+		response := helper.APIResponse("Failed to create API key", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := apikey.FormatCreatedAPIKey(newAPIKey, plaintext)
+	response := helper.APIResponse("API key has been created, copy it now because it won't be shown again", http.StatusCreated, "success", formatter)
+	helper.JSON(w, response, http.StatusCreated)
+}
+
+func (h *apiKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	apiKeys, err := h.apiKeyService.GetAPIKeys(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get API keys", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := apikey.FormatAPIKeys(apiKeys)
+	response := helper.APIResponse("List of API keys", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *apiKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID := chi.URLParam(r, "id")
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	err := h.apiKeyService.RevokeAPIKey(apiKeyID, currentUser.ID)
+	if err != nil {
+		if err == apikey.ErrAPIKeyNotFound {
+			response := helper.APIResponse("Failed to revoke API key", http.StatusNotFound, "error", err.Error())
+			helper.JSON(w, response, http.StatusNotFound)
+			return
+		}
+
+		response := helper.APIResponse("Failed to revoke API key", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	response := helper.APIResponse("API key has been revoked", http.StatusOK, "success", nil)
+	helper.JSON(w, response, http.StatusOK)
+}
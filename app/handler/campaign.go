@@ -5,6 +5,7 @@ import (
 	"funding-app/app/campaign"
 	"funding-app/app/helper"
 	"funding-app/app/key"
+	"funding-app/app/upload"
 	"funding-app/app/user"
 	"net/http"
 	"strconv"
@@ -16,16 +17,34 @@ import (
 
 type campaignHandler struct {
 	campaignService campaign.Service
+	uploadService   upload.Service
+	uploadConfig    helper.ImageUploadConfig
 }
 
-func NewCampaignHandler(campaignService campaign.Service) *campaignHandler {
-	return &campaignHandler{campaignService}
+func NewCampaignHandler(campaignService campaign.Service, uploadService upload.Service) *campaignHandler {
+	return &campaignHandler{campaignService, uploadService, helper.NewImageUploadConfig()}
 }
 
 func (h *campaignHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 
-	campaigns, err := h.campaignService.GetCampaigns(userID)
+	campaigns, err := h.campaignService.GetCampaigns(r.Context(), userID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get campaigns", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := campaign.FormatCampaigns(campaigns)
+	response := helper.APIResponse("List of campaigns", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *campaignHandler) GetUserCampaigns(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	campaigns, err := h.campaignService.GetCampaigns(r.Context(), currentUser.ID)
 	if err != nil {
 		response := helper.APIResponse("Failed to get campaigns", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
@@ -40,7 +59,7 @@ func (h *campaignHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
 func (h *campaignHandler) GetCampaignDetail(w http.ResponseWriter, r *http.Request) {
 	campaignID := chi.URLParam(r, "id")
 
-	detailCampaign, err := h.campaignService.GetCampaignDetail(campaignID)
+	detailCampaign, err := h.campaignService.GetCampaignDetail(r.Context(), campaignID)
 	if err != nil {
 		response := helper.APIResponse("Failed to get campaign", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
@@ -89,7 +108,7 @@ func (h *campaignHandler) CreateCampaign(w http.ResponseWriter, r *http.Request)
 	user := r.Context().Value(key.CtxAuthKey{}).(user.User)
 	input.User = user
 
-	newCampaign, err := h.campaignService.CreateCampaign(input)
+	newCampaign, err := h.campaignService.CreateCampaign(r.Context(), input)
 	if err != nil {
 		response := helper.APIResponse("Failed to create campaign", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
@@ -110,20 +129,16 @@ func (h *campaignHandler) UploadCampaignImage(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err := r.ParseMultipartForm(1024)
+	uploadedImage, err := helper.ParseImageUpload(w, r, "image", h.uploadConfig)
 	if err != nil {
-		response := helper.APIResponse("Failed to upload campaign image", http.StatusBadRequest, "error", err.Error())
-		helper.JSON(w, response, http.StatusBadRequest)
-		return
-	}
+		code := helper.UploadErrorCode(err)
 
-	uploadedFile, _, err := r.FormFile("image")
-	if err != nil {
-		response := helper.APIResponse("Failed to upload campaign image", http.StatusBadRequest, "error", err.Error())
-		helper.JSON(w, response, http.StatusBadRequest)
+		response := helper.APIResponse("Failed to upload campaign image", code, "error", err.Error())
+		helper.JSON(w, response, code)
 		return
 	}
 
+	uploadedFile := uploadedImage.File
 	defer uploadedFile.Close()
 
 	// get user data from middleware
@@ -137,22 +152,65 @@ func (h *campaignHandler) UploadCampaignImage(w http.ResponseWriter, r *http.Req
 		isPrimary = true
 	}
 
-	input := campaign.CreateCampaignImageInput{}
-	input.CampaignID = r.FormValue("campaign_id")
-	input.User = user
+	campaignID := r.FormValue("campaign_id")
+
+	// reject someone else's campaign before the file gets queued
+	detailCampaign, err := h.campaignService.GetCampaignDetail(r.Context(), campaignID)
+	if err != nil {
+		response := helper.APIResponse("Failed to upload campaign image", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	if detailCampaign.UserID != user.ID {
+		response := helper.APIResponse("Failed to upload campaign image", http.StatusBadRequest, "error", "not an owner of the campaign")
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	input := upload.CreateUploadInput{}
+	input.UserID = user.ID
+	input.Kind = upload.KindCampaignImage
+	input.TargetID = campaignID
 	input.IsPrimary = isPrimary
 
-	_, err = h.campaignService.UploadCampaignImage(input, uploadedFile)
+	newUpload, err := h.uploadService.Enqueue(input, uploadedFile)
+	if err == upload.ErrQueueFull {
+		response := helper.APIResponse("Failed to upload campaign image", http.StatusServiceUnavailable, "error", err.Error())
+		helper.JSON(w, response, http.StatusServiceUnavailable)
+		return
+	}
+
 	if err != nil {
 		response := helper.APIResponse("Failed to upload campaign image", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	data := M{
-		"is_uploaded": true,
+	formatter := upload.FormatUpload(newUpload)
+	response := helper.APIResponse("Campaign image upload accepted", http.StatusAccepted, "success", formatter)
+	helper.JSON(w, response, http.StatusAccepted)
+}
+
+func (h *campaignHandler) DeleteCampaignImage(w http.ResponseWriter, r *http.Request) {
+	imageID := chi.URLParam(r, "id")
+
+	// get user data from middleware
+	user := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	err := h.campaignService.DeleteCampaignImage(r.Context(), imageID, user.ID)
+	if err == campaign.ErrImageNotFound {
+		response := helper.APIResponse("Failed to delete campaign image", http.StatusNotFound, "error", err.Error())
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		response := helper.APIResponse("Failed to delete campaign image", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
 	}
 
-	response := helper.APIResponse("Success upload campaign image", http.StatusCreated, "success", data)
-	helper.JSON(w, response, http.StatusCreated)
+	response := helper.APIResponse("Campaign image has been deleted", http.StatusOK, "success", nil)
+	helper.JSON(w, response, http.StatusOK)
 }
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"funding-app/app/emailverification"
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/user"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type emailVerificationHandler struct {
+	emailVerificationService emailverification.Service
+}
+
+func NewEmailVerificationHandler(emailVerificationService emailverification.Service) *emailVerificationHandler {
+	return &emailVerificationHandler{emailVerificationService}
+}
+
+func (h *emailVerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	err := h.emailVerificationService.ResendVerification(currentUser.ID)
+	if err == emailverification.ErrTooManyRequests {
+		response := helper.APIResponse("Failed to send verification email", http.StatusTooManyRequests, "error", err.Error())
+		helper.JSON(w, response, http.StatusTooManyRequests)
+		return
+	}
+
+	if err == emailverification.ErrAlreadyVerified {
+		response := helper.APIResponse("Failed to send verification email", http.StatusConflict, "error", err.Error())
+		helper.JSON(w, response, http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		response := helper.APIResponse("Failed to send verification email", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	response := helper.APIResponse("Verification email has been sent", http.StatusAccepted, "success", nil)
+	helper.JSON(w, response, http.StatusAccepted)
+}
+
+func (h *emailVerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	verifiedUser, err := h.emailVerificationService.VerifyEmail(chi.URLParam(r, "token"))
+	if err != nil {
+		response := helper.APIResponse("Failed to verify email", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := user.FormatProfile(verifiedUser)
+	response := helper.APIResponse("Email has been verified", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
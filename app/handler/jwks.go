@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"fmt"
+	"funding-app/app/auth"
+	"funding-app/app/helper"
+	"net/http"
+	"time"
+)
+
+type jwksHandler struct {
+	authService auth.Service
+	maxAge      time.Duration
+}
+
+func NewJWKSHandler(authService auth.Service) *jwksHandler {
+	return &jwksHandler{authService, helper.GetEnvDuration("JWT_KEY_REFRESH_INTERVAL", time.Minute)}
+}
+
+// GetJWKS publishes the public keys in the plain JWK set format verifiers expect, not wrapped in an API response
+func (h *jwksHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+	helper.JSON(w, h.authService.JWKS(), http.StatusOK)
+}
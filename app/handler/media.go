@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"funding-app/app/helper"
+	"funding-app/app/storage"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type mediaHandler struct {
+	storage *storage.LocalStorage
+}
+
+func NewMediaHandler(storage *storage.LocalStorage) *mediaHandler {
+	return &mediaHandler{storage}
+}
+
+func (h *mediaHandler) ServeMedia(w http.ResponseWriter, r *http.Request) {
+	mediaKey := chi.URLParam(r, "*")
+
+	fullPath, err := h.storage.Path(mediaKey)
+	if err != nil {
+		response := helper.APIResponse("Failed to get media", http.StatusNotFound, "error", err.Error())
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		response := helper.APIResponse("Failed to get media", http.StatusNotFound, "error", "media not found")
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		response := helper.APIResponse("Failed to get media", http.StatusNotFound, "error", "media not found")
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(fullPath)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent takes care of range requests and conditional headers
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"funding-app/app/auth"
+	"funding-app/app/helper"
+	"funding-app/app/loginattempt"
+	"funding-app/app/mailer"
+	"funding-app/app/oauth"
+	"funding-app/app/twofactor"
+	"funding-app/app/user"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+const oauthStateCookie = "oauth_state"
+
+type oauthHandler struct {
+	oauthService        oauth.Service
+	authService         auth.Service
+	twoFactorService    twofactor.Service
+	userService         user.Service
+	loginAttemptService loginattempt.Service
+	secureCookies       bool
+}
+
+func NewOAuthHandler(oauthService oauth.Service, authService auth.Service, twoFactorService twofactor.Service, userService user.Service, loginAttemptService loginattempt.Service) *oauthHandler {
+	return &oauthHandler{
+		oauthService:        oauthService,
+		authService:         authService,
+		twoFactorService:    twoFactorService,
+		userService:         userService,
+		loginAttemptService: loginAttemptService,
+		secureCookies:       strings.HasPrefix(helper.GetEnv("APP_URL", "http://localhost:3000"), "https://"),
+	}
+}
+
+func (h *oauthHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	formatter := oauth.FormatProviders(h.oauthService.Providers())
+	response := helper.APIResponse("List of identity providers", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *oauthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	authorizationURL, state, err := h.oauthService.AuthorizationURL(provider)
+	if err != nil {
+		if err == oauth.ErrUnknownProvider {
+			response := helper.APIResponse("Failed to start login", http.StatusNotFound, "error", err.Error())
+			helper.JSON(w, response, http.StatusNotFound)
+			return
+		}
+
+		response := helper.APIResponse("Failed to start login", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// the callback only accepts the state together with this cookie, so nobody can finish their own
+	// login in someone else's browser
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/api/v1/oauth",
+		MaxAge:   int(h.oauthService.StateTTL().Seconds()),
+		Secure:   h.secureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	formatter := oauth.FormatAuthorization(provider, authorizationURL)
+	response := helper.APIResponse("Redirect the user to the authorization url", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+// Callback receives the code and state the provider redirected the user back with
+func (h *oauthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content must be application/json"
+
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := oauth.CallbackInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Login user failed", err)
+		return
+	}
+
+	if input.Locale == "" {
+		input.Locale = mailer.LocaleFromHeader(r.Header.Get("Accept-Language"))
+	}
+
+	if cookie, err := r.Cookie(oauthStateCookie); err == nil {
+		input.BrowserState = cookie.Value
+	}
+
+	// the state is single use, the cookie goes with it
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/api/v1/oauth",
+		MaxAge:   -1,
+		Secure:   h.secureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	loggedInUser, err := h.oauthService.Callback(provider, input)
+	if err != nil {
+		switch err {
+		case oauth.ErrUnknownProvider:
+			response := helper.APIResponse("Login user failed", http.StatusNotFound, "error", err.Error())
+			helper.JSON(w, response, http.StatusNotFound)
+		case user.ErrUserSuspended:
+			response := helper.APIResponse("Login user failed", http.StatusForbidden, "error", err.Error())
+			helper.JSON(w, response, http.StatusForbidden)
+		case oauth.ErrInvalidState, oauth.ErrInvalidIDToken, oauth.ErrEmailNotVerified:
+			response := helper.APIResponse("Login user failed", http.StatusUnauthorized, "error", err.Error())
+			helper.JSON(w, response, http.StatusUnauthorized)
+		case oauth.ErrProviderFailed:
+			response := helper.APIResponse("Login user failed", http.StatusBadGateway, "error", err.Error())
+			helper.JSON(w, response, http.StatusBadGateway)
+		default:
+			response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+			helper.JSON(w, response, http.StatusBadRequest)
+		}
+
+		return
+	}
+
+	completeLogin(w, r, loggedInUser, h.authService, h.twoFactorService, h.userService, h.loginAttemptService)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"funding-app/app/helper"
+	"funding-app/app/passwordreset"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type passwordResetHandler struct {
+	passwordResetService passwordreset.Service
+}
+
+func NewPasswordResetHandler(passwordResetService passwordreset.Service) *passwordResetHandler {
+	return &passwordResetHandler{passwordResetService}
+}
+
+func (h *passwordResetHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to request password reset", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := passwordreset.RequestPasswordResetInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to request password reset", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		var errors []string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, e.Error())
+		}
+
+		response := helper.APIResponse("Failed to request password reset", http.StatusUnprocessableEntity, "error", errors)
+		helper.JSON(w, response, http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = h.passwordResetService.RequestPasswordReset(input)
+	if err == passwordreset.ErrQueueFull {
+		response := helper.APIResponse("Failed to request password reset", http.StatusServiceUnavailable, "error", err.Error())
+		helper.JSON(w, response, http.StatusServiceUnavailable)
+		return
+	}
+
+	if err != nil {
+		response := helper.APIResponse("Failed to request password reset", http.StatusInternalServerError, "error", "please try again later")
+		helper.JSON(w, response, http.StatusInternalServerError)
+		return
+	}
+
+	response := helper.APIResponse("If the email is registered, a reset link has been sent", http.StatusAccepted, "success", nil)
+	helper.JSON(w, response, http.StatusAccepted)
+}
+
+func (h *passwordResetHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to reset password", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := passwordreset.ResetPasswordInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to reset password", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		var errors []string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, e.Error())
+		}
+
+		response := helper.APIResponse("Failed to reset password", http.StatusUnprocessableEntity, "error", errors)
+		helper.JSON(w, response, http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = h.passwordResetService.ResetPassword(chi.URLParam(r, "token"), input)
+	if respondPasswordPolicyError(w, "Failed to reset password", "password", err) {
+		return
+	}
+
+	if err != nil {
+		response := helper.APIResponse("Failed to reset password", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	response := helper.APIResponse("Password has been reset", http.StatusOK, "success", nil)
+	helper.JSON(w, response, http.StatusOK)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"funding-app/app/auth"
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/user"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type sessionHandler struct {
+	authService auth.Service
+}
+
+func NewSessionHandler(authService auth.Service) *sessionHandler {
+	return &sessionHandler{authService}
+}
+
+func (h *sessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// get session data from middleware
+	session := r.Context().Value(key.CtxSessionKey{}).(key.Session)
+
+	err := h.authService.Logout(session)
+	if err != nil {
+		response := helper.APIResponse("Failed to logout", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	response := helper.APIResponse("Logged out", http.StatusOK, "success", nil)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *sessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+	session := r.Context().Value(key.CtxSessionKey{}).(key.Session)
+
+	sessions, err := h.authService.GetSessions(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get sessions", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := auth.FormatSessions(sessions, session.ID)
+	response := helper.APIResponse("List of sessions", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *sessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	err := h.authService.RevokeSession(sessionID, currentUser.ID)
+	if err != nil {
+		if err == auth.ErrSessionNotFound {
+			response := helper.APIResponse("Failed to revoke session", http.StatusNotFound, "error", err.Error())
+			helper.JSON(w, response, http.StatusNotFound)
+			return
+		}
+
+		response := helper.APIResponse("Failed to revoke session", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	response := helper.APIResponse("Session has been revoked", http.StatusOK, "success", nil)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+// newSessionInput describes the client a new or refreshed session belongs to
+func newSessionInput(r *http.Request) auth.SessionInput {
+	return auth.SessionInput{
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// clientIP relies on the RealIP middleware to have resolved the headers of trusted proxies already
+func clientIP(r *http.Request) string {
+	ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ipAddress
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/transaction"
+	"funding-app/app/user"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type transactionHandler struct {
+	transactionService transaction.Service
+}
+
+func NewTransactionHandler(transactionService transaction.Service) *transactionHandler {
+	return &transactionHandler{transactionService}
+}
+
+func (h *transactionHandler) GetUserTransactions(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	v := validator.New()
+
+	input := transaction.GetUserTransactionsInput{}
+	input.UserID = currentUser.ID
+	input.Page, input.PerPage = helper.ParsePage(r)
+
+	// validate input
+	err := v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to get transactions", err)
+		return
+	}
+
+	transactions, total, err := h.transactionService.GetUserTransactions(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get transactions", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := helper.PaginatedFormatter{
+		Items:      transaction.FormatTransactions(transactions),
+		Pagination: helper.NewPagination(input.Page, input.PerPage, total),
+	}
+
+	response := helper.APIResponse("List of transactions", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"encoding/json"
+	"funding-app/app/auth"
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/loginattempt"
+	"funding-app/app/twofactor"
+	"funding-app/app/user"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+)
+
+type twoFactorHandler struct {
+	twoFactorService    twofactor.Service
+	authService         auth.Service
+	userService         user.Service
+	loginAttemptService loginattempt.Service
+}
+
+func NewTwoFactorHandler(twoFactorService twofactor.Service, authService auth.Service, userService user.Service, loginAttemptService loginattempt.Service) *twoFactorHandler {
+	return &twoFactorHandler{twoFactorService, authService, userService, loginAttemptService}
+}
+
+func (h *twoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	status, err := h.twoFactorService.GetStatus(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get two-factor status", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := twofactor.FormatStatus(status)
+	response := helper.APIResponse("Two-factor status", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *twoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	enrollment, err := h.twoFactorService.Enroll(currentUser)
+	if err != nil {
+		if err == twofactor.ErrAlreadyEnabled {
+			response := helper.APIResponse("Failed to enroll two-factor authentication", http.StatusConflict, "error", err.Error())
+			helper.JSON(w, response, http.StatusConflict)
+			return
+		}
+
+		response := helper.APIResponse("Failed to enroll two-factor authentication", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := twofactor.FormatEnrollment(enrollment)
+	response := helper.APIResponse("Scan the QR code and confirm with a code from your authenticator app", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *twoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to confirm two-factor authentication", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	v := validator.New()
+	input := twofactor.ConfirmInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to confirm two-factor authentication", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to confirm two-factor authentication", err)
+		return
+	}
+
+	recoveryCodes, err := h.twoFactorService.Confirm(currentUser.ID, input)
+	if err != nil {
+		response := helper.APIResponse("Failed to confirm two-factor authentication", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := twofactor.FormatRecoveryCodes(recoveryCodes)
+	response := helper.APIResponse("Two-factor authentication has been enabled, store the recovery codes somewhere safe", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *twoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to disable two-factor authentication", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	v := validator.New()
+	input := twofactor.DisableInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to disable two-factor authentication", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Failed to disable two-factor authentication", err)
+		return
+	}
+
+	err = h.twoFactorService.Disable(currentUser.ID, input)
+	if err != nil {
+		if err == user.ErrWrongPassword || err == twofactor.ErrInvalidCode {
+			response := helper.APIResponse("Failed to disable two-factor authentication", http.StatusForbidden, "error", err.Error())
+			helper.JSON(w, response, http.StatusForbidden)
+			return
+		}
+
+		response := helper.APIResponse("Failed to disable two-factor authentication", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	response := helper.APIResponse("Two-factor authentication has been disabled", http.StatusOK, "success", nil)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+// VerifyChallenge is the second step of a login for users with 2FA enabled
+func (h *twoFactorHandler) VerifyChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content must be application/json"
+
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := twofactor.VerifyChallengeInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		respondValidationError(w, "Login user failed", err)
+		return
+	}
+
+	loggedInUser, err := h.twoFactorService.VerifyChallenge(input)
+	if err != nil {
+		if err == user.ErrUserSuspended {
+			response := helper.APIResponse("Login user failed", http.StatusForbidden, "error", err.Error())
+			helper.JSON(w, response, http.StatusForbidden)
+			return
+		}
+
+		if err == twofactor.ErrInvalidChallenge || err == twofactor.ErrInvalidCode {
+			response := helper.APIResponse("Login user failed", http.StatusUnauthorized, "error", err.Error())
+			helper.JSON(w, response, http.StatusUnauthorized)
+			return
+		}
+
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	issueTokens(w, r, loggedInUser, h.authService, h.userService, h.loginAttemptService)
+}
+
+// completeLogin issues the tokens for a user who proved their first factor, with 2FA enabled
+// it only hands out a challenge and the tokens come after the code
+func completeLogin(w http.ResponseWriter, r *http.Request, loggedInUser user.User, authService auth.Service, twoFactorService twofactor.Service, userService user.Service, loginAttemptService loginattempt.Service) {
+	twoFactorEnabled, err := twoFactorService.IsEnabled(loggedInUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	if twoFactorEnabled {
+		challengeToken, err := twoFactorService.CreateChallenge(loggedInUser.ID)
+		if err != nil {
+			response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+			helper.JSON(w, response, http.StatusBadRequest)
+			return
+		}
+
+		formatter := twofactor.FormatChallenge(challengeToken, twoFactorService.ChallengeTTL())
+		response := helper.APIResponse("Two-factor authentication required", http.StatusOK, "success", formatter)
+		helper.JSON(w, response, http.StatusOK)
+		return
+	}
+
+	issueTokens(w, r, loggedInUser, authService, userService, loginAttemptService)
+}
+
+// issueTokens ends a login that passed every factor, only then the failures recorded against it are cleared
+func issueTokens(w http.ResponseWriter, r *http.Request, loggedInUser user.User, authService auth.Service, userService user.Service, loginAttemptService loginattempt.Service) {
+	err := userService.ResetFailedLogins(r.Context(), loggedInUser)
+	if err != nil {
+		log.Error(err)
+	}
+
+	loginAttemptService.RecordSuccess(loggedInUser.Email)
+
+	token, err := authService.GenerateToken(loggedInUser, newSessionInput(r))
+	if err != nil {
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := user.FormatUser(loggedInUser, token)
+	response := helper.APIResponse("Login successfully", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
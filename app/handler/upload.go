@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"funding-app/app/campaign"
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/upload"
+	"funding-app/app/user"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type uploadHandler struct {
+	uploadService upload.Service
+}
+
+func NewUploadHandler(uploadService upload.Service) *uploadHandler {
+	return &uploadHandler{uploadService}
+}
+
+// NewUploadProcessors wires the background upload workers to the services that own the uploaded files
+func NewUploadProcessors(userService user.Service, campaignService campaign.Service) upload.Processors {
+	return upload.Processors{
+		upload.KindAvatar: func(ctx context.Context, job upload.Upload, file *os.File) (upload.Result, error) {
+			updatedUser, err := userService.UploadAvatar(ctx, job.UserID, file)
+			if err != nil {
+				return upload.Result{}, err
+			}
+
+			return upload.Result{URL: updatedUser.AvatarFileName, Variants: updatedUser.AvatarVariants}, nil
+		},
+		upload.KindCampaignImage: func(ctx context.Context, job upload.Upload, file *os.File) (upload.Result, error) {
+			input := campaign.CreateCampaignImageInput{}
+			input.CampaignID = job.TargetID
+			input.IsPrimary = job.IsPrimary
+			input.User = user.User{ID: job.UserID}
+
+			campaignImage, err := campaignService.UploadCampaignImage(ctx, input, file)
+			if err != nil {
+				return upload.Result{}, err
+			}
+
+			return upload.Result{URL: campaignImage.FileName, Variants: campaignImage.Variants}, nil
+		},
+	}
+}
+
+func (h *uploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	uploadID := chi.URLParam(r, "id")
+
+	// get user data from middleware
+	user := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	detailUpload, err := h.uploadService.GetUpload(uploadID, user.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get upload", http.StatusNotFound, "error", err.Error())
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	formatter := upload.FormatUpload(detailUpload)
+	response := helper.APIResponse("Detail of upload", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+// UploadEvents streams status changes as server-sent events until the upload is done
+func (h *uploadHandler) UploadEvents(w http.ResponseWriter, r *http.Request) {
+	uploadID := chi.URLParam(r, "id")
+
+	// get user data from middleware
+	user := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response := helper.APIResponse("Failed to stream upload", http.StatusInternalServerError, "error", "streaming is not supported")
+		helper.JSON(w, response, http.StatusInternalServerError)
+		return
+	}
+
+	// subscribe before reading the current state so no change slips in between
+	events, unsubscribe := h.uploadService.Subscribe(uploadID)
+	defer unsubscribe()
+
+	detailUpload, err := h.uploadService.GetUpload(uploadID, user.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to stream upload", http.StatusNotFound, "error", err.Error())
+		helper.JSON(w, response, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(event upload.Upload) {
+		data, _ := json.Marshal(upload.FormatUpload(event))
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Status, data)
+		flusher.Flush()
+	}
+
+	writeEvent(detailUpload)
+	if detailUpload.IsDone() {
+		return
+	}
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err := fmt.Fprint(w, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+
+			flusher.Flush()
+		case event := <-events:
+			writeEvent(event)
+			if event.IsDone() {
+				return
+			}
+		}
+	}
+}
@@ -3,27 +3,44 @@ package handler
 import (
 	"encoding/json"
 	"funding-app/app/auth"
+	"funding-app/app/emailverification"
 	"funding-app/app/helper"
 	"funding-app/app/key"
+	"funding-app/app/loginattempt"
+	"funding-app/app/mailer"
+	"funding-app/app/twofactor"
+	"funding-app/app/upload"
 	"funding-app/app/user"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
 )
 
 // alias map
 type M map[string]interface{}
 
 type userHandler struct {
-	userService user.Service
-	authService auth.Service
+	userService              user.Service
+	authService              auth.Service
+	uploadService            upload.Service
+	emailVerificationService emailverification.Service
+	loginAttemptService      loginattempt.Service
+	twoFactorService         twofactor.Service
+	uploadConfig             helper.ImageUploadConfig
 }
 
-func NewUserHandler(userService user.Service, authService auth.Service) *userHandler {
+func NewUserHandler(userService user.Service, authService auth.Service, uploadService upload.Service, emailVerificationService emailverification.Service, loginAttemptService loginattempt.Service, twoFactorService twofactor.Service) *userHandler {
 	return &userHandler{
-		userService: userService,
-		authService: authService,
+		userService:              userService,
+		authService:              authService,
+		uploadService:            uploadService,
+		emailVerificationService: emailVerificationService,
+		loginAttemptService:      loginAttemptService,
+		twoFactorService:         twoFactorService,
+		uploadConfig:             helper.NewImageUploadConfig(),
 	}
 }
 
@@ -60,14 +77,28 @@ func (h *userHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newUser, err := h.userService.RegisterUser(input)
+	if input.Locale == "" {
+		input.Locale = mailer.LocaleFromHeader(r.Header.Get("Accept-Language"))
+	}
+
+	newUser, err := h.userService.RegisterUser(r.Context(), input)
+	if respondPasswordPolicyError(w, "Failed to register user", "password", err) {
+		return
+	}
+
 	if err != nil {
 		response := helper.APIResponse("Failed to register user", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(newUser.ID)
+	// a failed email can be resent later, it shouldn't fail the registration
+	err = h.emailVerificationService.SendVerification(newUser)
+	if err != nil {
+		log.Error(err)
+	}
+
+	token, err := h.authService.GenerateToken(newUser, newSessionInput(r))
 	if err != nil {
 		response := helper.APIResponse("Failed to register user", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
@@ -112,23 +143,51 @@ func (h *userHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	loggedInUser, err := h.userService.LoginUser(input)
-	if err != nil {
-		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
+	ipAddress := clientIP(r)
+
+	// slow down repeated failures, a blocked address gets the same answer as a wrong password
+	delay, err := h.loginAttemptService.Check(input.Email, ipAddress)
+	if err != nil && err != loginattempt.ErrTooManyAttempts {
+		log.Error(err)
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		}
+	}
+
+	if err == loginattempt.ErrTooManyAttempts {
+		h.loginAttemptService.RecordFailure(input.Email, ipAddress)
+
+		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", user.ErrInvalidCredentials.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(loggedInUser.ID)
+	loggedInUser, err := h.userService.LoginUser(r.Context(), input)
 	if err != nil {
+		if err == user.ErrUserSuspended {
+			response := helper.APIResponse("Login user failed", http.StatusForbidden, "error", err.Error())
+			helper.JSON(w, response, http.StatusForbidden)
+			return
+		}
+
+		if err == user.ErrInvalidCredentials {
+			h.loginAttemptService.RecordFailure(input.Email, ipAddress)
+		}
+
 		response := helper.APIResponse("Login user failed", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	formatter := user.FormatUser(loggedInUser, token)
-	response := helper.APIResponse("Login successfully", http.StatusOK, "success", formatter)
-	helper.JSON(w, response, http.StatusOK)
+	completeLogin(w, r, loggedInUser, h.authService, h.twoFactorService, h.userService, h.loginAttemptService)
 }
 
 func (h *userHandler) IsEmailAvailable(w http.ResponseWriter, r *http.Request) {
@@ -164,7 +223,7 @@ func (h *userHandler) IsEmailAvailable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAvailable, err := h.userService.IsEmailAvailable(input)
+	isAvailable, err := h.userService.IsEmailAvailable(r.Context(), input)
 	if err != nil {
 		response := helper.APIResponse("Checking email failed", http.StatusUnprocessableEntity, "error", err.Error())
 		helper.JSON(w, response, http.StatusUnprocessableEntity)
@@ -188,38 +247,41 @@ func (h *userHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := r.ParseMultipartForm(1024)
+	uploadedImage, err := helper.ParseImageUpload(w, r, "avatar", h.uploadConfig)
 	if err != nil {
-		response := helper.APIResponse("Failed to upload avatar", http.StatusBadRequest, "error", err.Error())
-		helper.JSON(w, response, http.StatusBadRequest)
-		return
-	}
+		code := helper.UploadErrorCode(err)
 
-	uploadedFile, _, err := r.FormFile("avatar")
-	if err != nil {
-		response := helper.APIResponse("Failed to upload avatar", http.StatusBadRequest, "error", err.Error())
-		helper.JSON(w, response, http.StatusBadRequest)
+		response := helper.APIResponse("Failed to upload avatar", code, "error", err.Error())
+		helper.JSON(w, response, code)
 		return
 	}
 
+	uploadedFile := uploadedImage.File
 	defer uploadedFile.Close()
 
 	// get user data from middleware
 	user := r.Context().Value(key.CtxAuthKey{}).(user.User)
 
-	_, err = h.userService.UploadAvatar(user.ID, uploadedFile)
+	input := upload.CreateUploadInput{}
+	input.UserID = user.ID
+	input.Kind = upload.KindAvatar
+
+	newUpload, err := h.uploadService.Enqueue(input, uploadedFile)
+	if err == upload.ErrQueueFull {
+		response := helper.APIResponse("Failed to upload avatar", http.StatusServiceUnavailable, "error", err.Error())
+		helper.JSON(w, response, http.StatusServiceUnavailable)
+		return
+	}
+
 	if err != nil {
 		response := helper.APIResponse("Failed to upload avatar", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	data := M{
-		"is_uploaded": true,
-	}
-
-	response := helper.APIResponse("Success upload avatar", http.StatusCreated, "success", data)
-	helper.JSON(w, response, http.StatusCreated)
+	formatter := upload.FormatUpload(newUpload)
+	response := helper.APIResponse("Avatar upload accepted", http.StatusAccepted, "success", formatter)
+	helper.JSON(w, response, http.StatusAccepted)
 }
 
 func (h *userHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
@@ -232,7 +294,7 @@ func (h *userHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	v := validator.New()
-	input := key.Token{}
+	input := auth.RefreshTokenInput{}
 
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
@@ -255,30 +317,172 @@ func (h *userHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := auth.NewJwtService().ValidateRefreshToken(input)
+	token, err := h.authService.RefreshToken(input, newSessionInput(r))
 	if err != nil {
+		if err == auth.ErrInvalidRefreshToken || err == auth.ErrRefreshTokenReused {
+			response := helper.APIResponse("Failed to refresh token", http.StatusUnauthorized, "error", err.Error())
+			helper.JSON(w, response, http.StatusUnauthorized)
+			return
+		}
+
 		response := helper.APIResponse("Failed to refresh token", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	detailUser, err := h.userService.GetUserByID(user.ID)
+	response := helper.APIResponse("Success create refresh token", http.StatusCreated, "success", token)
+	helper.JSON(w, response, http.StatusCreated)
+}
+
+func (h *userHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	formatter := user.FormatProfile(currentUser)
+	response := helper.APIResponse("Detail of profile", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+func (h *userHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to update profile", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := user.UpdateProfileInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
-		response := helper.APIResponse("Failed to refresh token", http.StatusBadRequest, "error", err.Error())
+		response := helper.APIResponse("Failed to update profile", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(detailUser.ID)
+	// validate input
+	err = v.Struct(input)
 	if err != nil {
-		response := helper.APIResponse("Failed to refresh token", http.StatusBadRequest, "error", err.Error())
+		var errors []string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, e.Error())
+		}
+
+		response := helper.APIResponse("Failed to update profile", http.StatusUnprocessableEntity, "error", errors)
+		helper.JSON(w, response, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	updatedUser, err := h.userService.UpdateProfile(r.Context(), currentUser.ID, input)
+	if err == user.ErrEmailAlreadyUsed {
+		response := helper.APIResponse("Failed to update profile", http.StatusConflict, "error", err.Error())
+		helper.JSON(w, response, http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		response := helper.APIResponse("Failed to update profile", http.StatusBadRequest, "error", err.Error())
 		helper.JSON(w, response, http.StatusBadRequest)
 		return
 	}
 
-	jwtToken := key.Token{}
-	jwtToken.AccessToken = token.AccessToken
+	if updatedUser.Email != currentUser.Email {
+		err = h.emailVerificationService.SendVerification(updatedUser)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	formatter := user.FormatProfile(updatedUser)
+	response := helper.APIResponse("Profile has been updated", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
 
-	response := helper.APIResponse("Success create refresh token", http.StatusCreated, "success", jwtToken)
-	helper.JSON(w, response, http.StatusCreated)
+func (h *userHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorMessage := "Content type must be application/json"
+
+		response := helper.APIResponse("Failed to change password", http.StatusBadRequest, "error", errorMessage)
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	input := user.ChangePasswordInput{}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to change password", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// validate input
+	err = v.Struct(input)
+	if err != nil {
+		var errors []string
+
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, e.Error())
+		}
+
+		response := helper.APIResponse("Failed to change password", http.StatusUnprocessableEntity, "error", errors)
+		helper.JSON(w, response, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	updatedUser, err := h.userService.ChangePassword(r.Context(), currentUser.ID, input)
+	if respondPasswordPolicyError(w, "Failed to change password", "new_password", err) {
+		return
+	}
+
+	if err == user.ErrWrongPassword {
+		response := helper.APIResponse("Failed to change password", http.StatusForbidden, "error", err.Error())
+		helper.JSON(w, response, http.StatusForbidden)
+		return
+	}
+
+	if err != nil {
+		response := helper.APIResponse("Failed to change password", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// every other token is revoked now, hand the caller a fresh pair
+	token, err := h.authService.GenerateToken(updatedUser, newSessionInput(r))
+	if err != nil {
+		response := helper.APIResponse("Failed to change password", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := user.FormatUser(updatedUser, token)
+	response := helper.APIResponse("Password has been changed", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
+}
+
+// BecomeCreator is the self-service onboarding for campaign creators
+func (h *userHandler) BecomeCreator(w http.ResponseWriter, r *http.Request) {
+	// get user data from middleware
+	currentUser := r.Context().Value(key.CtxAuthKey{}).(user.User)
+
+	updatedUser, err := h.userService.BecomeCreator(r.Context(), currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to become a creator", http.StatusBadRequest, "error", err.Error())
+		helper.JSON(w, response, http.StatusBadRequest)
+		return
+	}
+
+	formatter := user.FormatProfile(updatedUser)
+	response := helper.APIResponse("You can now create campaigns", http.StatusOK, "success", formatter)
+	helper.JSON(w, response, http.StatusOK)
 }
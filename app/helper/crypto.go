@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+var ErrDecrypt = errors.New("data can't be decrypted")
+
+// Encrypt seals plain with AES-GCM under a key derived from secret, the nonce is prepended to the result
+func Encrypt(secret []byte, plain []byte) (string, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plain, nil)), nil
+}
+
+func Decrypt(secret []byte, encrypted string) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+func newGCM(secret []byte) (cipher.AEAD, error) {
+	sum := sha256.Sum256(secret)
+
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
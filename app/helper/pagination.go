@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+type Pagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+type PaginatedFormatter struct {
+	Items      interface{} `json:"items"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+// ParsePage reads the page and per_page query params, invalid values fall back to the defaults
+func ParsePage(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	return page, perPage
+}
+
+func NewPagination(page int, perPage int, total int) Pagination {
+	pagination := Pagination{}
+	pagination.Page = page
+	pagination.PerPage = perPage
+	pagination.Total = total
+	pagination.TotalPages = (total + perPage - 1) / perPage
+
+	return pagination
+}
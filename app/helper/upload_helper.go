@@ -0,0 +1,155 @@
+package helper
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+type (
+	UploadError struct {
+		Code    int
+		Message string
+	}
+
+	ImageUploadConfig struct {
+		MaxBytes  int64
+		MinWidth  int
+		MinHeight int
+		MaxWidth  int
+		MaxHeight int
+	}
+
+	UploadedImage struct {
+		File        multipart.File
+		ContentType string
+		Width       int
+		Height      int
+		Size        int64
+	}
+)
+
+func (e *UploadError) Error() string {
+	return e.Message
+}
+
+func UploadErrorCode(err error) int {
+	if uploadErr, ok := err.(*UploadError); ok {
+		return uploadErr.Code
+	}
+
+	return http.StatusBadRequest
+}
+
+func NewImageUploadConfig() ImageUploadConfig {
+	return ImageUploadConfig{
+		MaxBytes:  int64(GetEnvInt("UPLOAD_MAX_BYTES", 5<<20)),
+		MinWidth:  GetEnvInt("UPLOAD_IMAGE_MIN_WIDTH", 100),
+		MinHeight: GetEnvInt("UPLOAD_IMAGE_MIN_HEIGHT", 100),
+		MaxWidth:  GetEnvInt("UPLOAD_IMAGE_MAX_WIDTH", 6000),
+		MaxHeight: GetEnvInt("UPLOAD_IMAGE_MAX_HEIGHT", 6000),
+	}
+}
+
+// ParseImageUpload limits the request body, reads the multipart field and validates the image inside it
+func ParseImageUpload(w http.ResponseWriter, r *http.Request, field string, config ImageUploadConfig) (UploadedImage, error) {
+	uploadedImage := UploadedImage{}
+
+	if r.ContentLength > config.MaxBytes {
+		return uploadedImage, errFileTooLarge(config.MaxBytes)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, config.MaxBytes)
+
+	err := r.ParseMultipartForm(1024)
+	if err != nil {
+		if strings.Contains(err.Error(), "request body too large") {
+			return uploadedImage, errFileTooLarge(config.MaxBytes)
+		}
+
+		return uploadedImage, &UploadError{http.StatusBadRequest, err.Error()}
+	}
+
+	file, fileHeader, err := r.FormFile(field)
+	if err != nil {
+		return uploadedImage, &UploadError{http.StatusBadRequest, err.Error()}
+	}
+
+	uploadedImage, err = ValidateImage(file, config)
+	if err != nil {
+		file.Close()
+		return uploadedImage, err
+	}
+
+	uploadedImage.Size = fileHeader.Size
+	return uploadedImage, nil
+}
+
+func ValidateImage(file multipart.File, config ImageUploadConfig) (UploadedImage, error) {
+	uploadedImage := UploadedImage{File: file}
+
+	// detect the real type from the magic bytes, the client header can't be trusted
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return uploadedImage, &UploadError{http.StatusBadRequest, err.Error()}
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if !allowedImageTypes[contentType] {
+		message := fmt.Sprintf("unsupported file type %s, only jpeg, png, webp and gif are allowed", contentType)
+		return uploadedImage, &UploadError{http.StatusUnsupportedMediaType, message}
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return uploadedImage, &UploadError{http.StatusBadRequest, err.Error()}
+	}
+
+	imageConfig, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return uploadedImage, &UploadError{http.StatusUnsupportedMediaType, "image is corrupted or can't be decoded"}
+	}
+
+	if imageConfig.Width > config.MaxWidth || imageConfig.Height > config.MaxHeight {
+		message := fmt.Sprintf("image dimension %dx%d exceeds the maximum of %dx%d", imageConfig.Width, imageConfig.Height, config.MaxWidth, config.MaxHeight)
+		return uploadedImage, &UploadError{http.StatusRequestEntityTooLarge, message}
+	}
+
+	if imageConfig.Width < config.MinWidth || imageConfig.Height < config.MinHeight {
+		message := fmt.Sprintf("image dimension %dx%d is below the minimum of %dx%d", imageConfig.Width, imageConfig.Height, config.MinWidth, config.MinHeight)
+		return uploadedImage, &UploadError{http.StatusUnsupportedMediaType, message}
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return uploadedImage, &UploadError{http.StatusBadRequest, err.Error()}
+	}
+
+	uploadedImage.ContentType = contentType
+	uploadedImage.Width = imageConfig.Width
+	uploadedImage.Height = imageConfig.Height
+
+	return uploadedImage, nil
+}
+
+func errFileTooLarge(maxBytes int64) error {
+	message := fmt.Sprintf("file is too large, maximum size is %d bytes", maxBytes)
+	return &UploadError{http.StatusRequestEntityTooLarge, message}
+}
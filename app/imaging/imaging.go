@@ -0,0 +1,248 @@
+package imaging
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"strconv"
+
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	Original = "original"
+	Thumb    = "thumb"
+	Card     = "card"
+	Hero     = "hero"
+
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+type (
+	Variant struct {
+		Name   string
+		Width  int
+		Height int
+		// crop to fill the whole box instead of fitting inside it
+		Crop bool
+	}
+
+	ProcessedImage struct {
+		Name        string
+		ContentType string
+		Width       int
+		Height      int
+		Data        []byte
+	}
+
+	// Variants maps a variant name to its stored url
+	Variants map[string]string
+
+	encodeFunc func(w io.Writer, img image.Image, quality int) error
+
+	Processor struct {
+		Format  string
+		Quality int
+	}
+)
+
+var (
+	AvatarVariants = []Variant{
+		{Name: Thumb, Width: 96, Height: 96, Crop: true},
+		{Name: Card, Width: 320, Height: 320, Crop: true},
+	}
+
+	CampaignImageVariants = []Variant{
+		{Name: Thumb, Width: 160, Height: 120, Crop: true},
+		{Name: Card, Width: 640, Height: 480, Crop: true},
+		{Name: Hero, Width: 1600, Height: 900, Crop: false},
+	}
+
+	encoders = map[string]encodeFunc{
+		FormatJPEG: func(w io.Writer, img image.Image, quality int) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		},
+	}
+
+	contentTypes = map[string]string{
+		FormatJPEG: "image/jpeg",
+		FormatWebP: "image/webp",
+	}
+)
+
+func NewProcessor() (*Processor, error) {
+	format := os.Getenv("IMAGE_OUTPUT_FORMAT")
+	if format == "" {
+		format = FormatJPEG
+	}
+
+	if _, ok := encoders[format]; !ok {
+		if format == FormatWebP {
+			return nil, errors.New("webp output requires building with -tags webp")
+		}
+
+		return nil, fmt.Errorf("unsupported image output format %s", format)
+	}
+
+	quality, err := strconv.Atoi(os.Getenv("IMAGE_QUALITY"))
+	if err != nil || quality < 1 || quality > 100 {
+		quality = 85
+	}
+
+	return &Processor{Format: format, Quality: quality}, nil
+}
+
+// Process decodes the upload, fixes its orientation and re-encodes the original plus every variant,
+// re-encoding drops EXIF and GPS metadata on the way
+func (p *Processor) Process(r io.Reader, variants []Variant) ([]ProcessedImage, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	src := toNRGBA(decoded)
+	src = applyOrientation(src, readOrientation(data))
+
+	processedImages := []ProcessedImage{}
+
+	original, err := p.encode(Original, src)
+	if err != nil {
+		return nil, err
+	}
+
+	processedImages = append(processedImages, original)
+
+	for _, variant := range variants {
+		processed, err := p.encode(variant.Name, resize(src, variant))
+		if err != nil {
+			return nil, err
+		}
+
+		processedImages = append(processedImages, processed)
+	}
+
+	return processedImages, nil
+}
+
+func (p *Processor) encode(name string, img *image.NRGBA) (ProcessedImage, error) {
+	processed := ProcessedImage{
+		Name:        name,
+		ContentType: contentTypes[p.Format],
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}
+
+	var buf bytes.Buffer
+
+	// jpeg has no alpha channel, keep transparent images as png
+	if p.Format == FormatJPEG && !img.Opaque() {
+		err := png.Encode(&buf, img)
+		if err != nil {
+			return processed, err
+		}
+
+		processed.ContentType = "image/png"
+		processed.Data = buf.Bytes()
+		return processed, nil
+	}
+
+	err := encoders[p.Format](&buf, img, p.Quality)
+	if err != nil {
+		return processed, err
+	}
+
+	processed.Data = buf.Bytes()
+	return processed, nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	return dst
+}
+
+func resize(src *image.NRGBA, variant Variant) *image.NRGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	srcRect := src.Bounds()
+
+	dstWidth, dstHeight := variant.Width, variant.Height
+	if variant.Crop {
+		// cut the center of the source to the aspect ratio of the box
+		if srcWidth*variant.Height > srcHeight*variant.Width {
+			cropWidth := srcHeight * variant.Width / variant.Height
+			offset := (srcWidth - cropWidth) / 2
+			srcRect = image.Rect(offset, 0, offset+cropWidth, srcHeight)
+		} else {
+			cropHeight := srcWidth * variant.Height / variant.Width
+			offset := (srcHeight - cropHeight) / 2
+			srcRect = image.Rect(0, offset, srcWidth, offset+cropHeight)
+		}
+
+		if srcRect.Dx() < dstWidth {
+			dstWidth, dstHeight = srcRect.Dx(), srcRect.Dy()
+		}
+	} else {
+		// fit inside the box, never upscale
+		dstWidth, dstHeight = srcWidth, srcHeight
+		if dstWidth > variant.Width {
+			dstWidth, dstHeight = variant.Width, dstHeight*variant.Width/dstWidth
+		}
+
+		if dstHeight > variant.Height {
+			dstWidth, dstHeight = dstWidth*variant.Height/dstHeight, variant.Height
+		}
+	}
+
+	if dstWidth < 1 {
+		dstWidth = 1
+	}
+
+	if dstHeight < 1 {
+		dstHeight = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Src, nil)
+
+	return dst
+}
+
+func (v Variants) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(v)
+}
+
+func (v *Variants) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		*v = Variants{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, v)
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	default:
+		return fmt.Errorf("unsupported variants type %T", src)
+	}
+}
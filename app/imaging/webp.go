@@ -0,0 +1,17 @@
+//go:build webp
+// +build webp
+
+package imaging
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+func init() {
+	encoders[FormatWebP] = func(w io.Writer, img image.Image, quality int) error {
+		return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+	}
+}
@@ -0,0 +1,78 @@
+package jobqueue
+
+import (
+	"context"
+	"time"
+)
+
+// Process handles one job by its id, the job itself lives in the database of its package
+type Process func(ctx context.Context, ID string)
+
+// Runner feeds job ids to a pool of workers, the queue only holds ids so nothing is lost
+// on a restart as long as unfinished jobs are passed to Start again
+type Runner struct {
+	workers int
+	queue   chan string
+	process Process
+}
+
+func NewRunner(workers int, queueSize int, process Process) *Runner {
+	return &Runner{
+		workers: workers,
+		queue:   make(chan string, queueSize),
+		process: process,
+	}
+}
+
+// Start spawns the worker pool and queues the jobs left over from a previous run
+func (r *Runner) Start(ctx context.Context, unfinishedIDs []string) {
+	for i := 0; i < r.workers; i++ {
+		go r.work(ctx)
+	}
+
+	for _, ID := range unfinishedIDs {
+		go r.requeue(ctx, ID, 0)
+	}
+}
+
+// Enqueue never waits, false means the queue is full and the caller decides what happens to the job
+func (r *Runner) Enqueue(ID string) bool {
+	select {
+	case r.queue <- ID:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retry queues the job again after a backoff that doubles with every attempt
+func (r *Runner) Retry(ctx context.Context, ID string, attempts int) {
+	go r.requeue(ctx, ID, time.Duration(1<<uint(attempts))*time.Second)
+}
+
+func (r *Runner) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ID := <-r.queue:
+			r.process(ctx, ID)
+		}
+	}
+}
+
+func (r *Runner) requeue(ctx context.Context, ID string, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	select {
+	case <-ctx.Done():
+	case r.queue <- ID:
+	}
+}
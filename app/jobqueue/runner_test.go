@@ -0,0 +1,70 @@
+package jobqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunnerProcessesQueuedAndUnfinishedJobs(t *testing.T) {
+	processed := make(chan string, 4)
+	runner := NewRunner(2, 4, func(ctx context.Context, ID string) {
+		processed <- ID
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner.Start(ctx, []string{"left-over"})
+
+	if !runner.Enqueue("new") {
+		t.Fatal("Enqueue reported a full queue")
+	}
+
+	seen := map[string]bool{}
+	for len(seen) < 2 {
+		select {
+		case ID := <-processed:
+			seen[ID] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("processed %v, want left-over and new", seen)
+		}
+	}
+}
+
+func TestEnqueueDoesNotWaitOnAFullQueue(t *testing.T) {
+	// without Start nothing drains the queue
+	runner := NewRunner(1, 1, func(ctx context.Context, ID string) {})
+
+	if !runner.Enqueue("first") {
+		t.Fatal("Enqueue reported a full queue")
+	}
+
+	if runner.Enqueue("second") {
+		t.Error("Enqueue accepted more jobs than the queue holds")
+	}
+}
+
+func TestRetryWaitsForTheBackoff(t *testing.T) {
+	processed := make(chan time.Time, 1)
+	runner := NewRunner(1, 1, func(ctx context.Context, ID string) {
+		processed <- time.Now()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner.Start(ctx, nil)
+
+	retriedAt := time.Now()
+	runner.Retry(ctx, "job", 0)
+
+	select {
+	case at := <-processed:
+		if at.Sub(retriedAt) < time.Second {
+			t.Errorf("retried after %v, want at least 1s", at.Sub(retriedAt))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("retried job was never processed")
+	}
+}
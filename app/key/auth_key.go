@@ -1,9 +1,41 @@
 package key
 
+import "time"
+
 type CtxAuthKey struct{}
 
+type CtxSessionKey struct{}
+
+type CtxAPIKeyKey struct{}
+
+// Session identifies the session and the access token of an authenticated request
+type Session struct {
+	ID        string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+// APIKey is set instead of a session when a request authenticated with an API key
+type APIKey struct {
+	ID     string
+	Scopes []string
+}
+
+// Allows reports whether the key was granted the scope
+func (k APIKey) Allows(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
 type FileUploadResponse struct {
+	MediaID   string
 	SecureURL string
+	Variants  map[string]string
 	Err       error
 }
 
@@ -0,0 +1,11 @@
+package loginattempt
+
+import "time"
+
+// Failure is one failed login, successful logins clear the failures of their email
+type Failure struct {
+	ID        string
+	Email     string
+	IPAddress string
+	CreatedAt time.Time
+}
@@ -0,0 +1,104 @@
+package loginattempt
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Repository interface {
+	Save(ctx context.Context, failure Failure) (Failure, error)
+	CountSince(ctx context.Context, email string, ipAddress string, since time.Time) (int, int, error)
+	DeleteByEmail(ctx context.Context, email string) error
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewLoginAttemptRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, failure Failure) (Failure, error) {
+	sqlQuery := "INSERT INTO login_failures (id, email, ip_address, created_at) VALUES($1, $2, $3, $4)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return failure, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx, failure.ID, failure.Email, failure.IPAddress, now.Format(layoutDateTime))
+	if err != nil {
+		return failure, err
+	}
+
+	failure.CreatedAt = now
+	return failure, nil
+}
+
+// CountSince returns the failures for the email and for the IP address
+func (r *repository) CountSince(ctx context.Context, email string, ipAddress string, since time.Time) (int, int, error) {
+	sqlQuery := "SELECT COUNT(*) FILTER (WHERE email = $1), COUNT(*) FILTER (WHERE ip_address = $2) FROM login_failures WHERE (email = $1 OR ip_address = $2) AND created_at > $3"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer stmt.Close()
+
+	var emailFailures, ipFailures int
+
+	err = stmt.QueryRowContext(ctx, email, ipAddress, since.Format(layoutDateTime)).Scan(&emailFailures, &ipFailures)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return emailFailures, ipFailures, nil
+}
+
+func (r *repository) DeleteByEmail(ctx context.Context, email string) error {
+	sqlQuery := "DELETE FROM login_failures WHERE email = $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, email)
+	return err
+}
+
+func (r *repository) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	sqlQuery := "DELETE FROM login_failures WHERE created_at <= $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, before.Format(layoutDateTime))
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
@@ -0,0 +1,138 @@
+package loginattempt
+
+import (
+	"context"
+	"errors"
+	"funding-app/app/helper"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed logins from this address")
+)
+
+type Config struct {
+	Window           time.Duration
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	MaxFailuresPerIP int
+}
+
+type Service interface {
+	Check(email string, ipAddress string) (time.Duration, error)
+	RecordFailure(email string, ipAddress string)
+	RecordSuccess(email string)
+	Start(ctx context.Context)
+}
+
+type service struct {
+	loginAttemptRepository Repository
+	config                 Config
+}
+
+func NewLoginAttemptService(loginAttemptRepository Repository) Service {
+	return &service{
+		loginAttemptRepository: loginAttemptRepository,
+		config: Config{
+			Window:           helper.GetEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+			FreeAttempts:     helper.GetEnvInt("LOGIN_FREE_ATTEMPTS", 2),
+			BaseDelay:        helper.GetEnvDuration("LOGIN_DELAY_BASE", 500*time.Millisecond),
+			MaxDelay:         helper.GetEnvDuration("LOGIN_DELAY_MAX", 10*time.Second),
+			MaxFailuresPerIP: helper.GetEnvInt("LOGIN_MAX_FAILURES_PER_IP", 50),
+		},
+	}
+}
+
+// Check returns how long the login has to wait, the delay doubles with every recent failure
+// for the email or the IP address, whichever has more
+func (s *service) Check(email string, ipAddress string) (time.Duration, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	emailFailures, ipFailures, err := s.loginAttemptRepository.CountSince(ctx, normalizeEmail(email), ipAddress, time.Now().Add(-s.config.Window))
+	if err != nil {
+		return 0, err
+	}
+
+	failures := emailFailures
+	if ipFailures > failures {
+		failures = ipFailures
+	}
+
+	delay := s.delay(failures)
+
+	if ipFailures >= s.config.MaxFailuresPerIP {
+		return delay, ErrTooManyAttempts
+	}
+
+	return delay, nil
+}
+
+func (s *service) RecordFailure(email string, ipAddress string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failure := Failure{}
+	failure.ID = helper.GenerateID()
+	failure.Email = normalizeEmail(email)
+	failure.IPAddress = ipAddress
+
+	_, err := s.loginAttemptRepository.Save(ctx, failure)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (s *service) RecordSuccess(email string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := s.loginAttemptRepository.DeleteByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// Start prunes failures that fell out of the window
+func (s *service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.config.Window)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := s.loginAttemptRepository.DeleteBefore(ctx, time.Now().Add(-s.config.Window))
+				if err != nil {
+					log.Error(err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *service) delay(failures int) time.Duration {
+	if failures < s.config.FreeAttempts {
+		return 0
+	}
+
+	delay := s.config.BaseDelay
+	for i := s.config.FreeAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= s.config.MaxDelay {
+			return s.config.MaxDelay
+		}
+	}
+
+	return delay
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
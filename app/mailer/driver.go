@@ -0,0 +1,186 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Driver interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewDriver picks the driver from MAIL_DRIVER, smtp is the default
+func NewDriver() (Driver, error) {
+	switch os.Getenv("MAIL_DRIVER") {
+	case "file":
+		return NewFileDriver(os.Getenv("MAIL_FILE_DIR"))
+	case "log":
+		return &logDriver{}, nil
+	case "memory":
+		return NewMemoryDriver(), nil
+	default:
+		return NewSMTPDriver(), nil
+	}
+}
+
+type smtpDriver struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPDriver defaults to localhost:1025 so development mail lands in a local catch-all like MailHog
+func NewSMTPDriver() Driver {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "1025"
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return &smtpDriver{
+		addr: host + ":" + port,
+		auth: auth,
+		from: mailFrom(),
+	}
+}
+
+func (d *smtpDriver) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body, err := buildMIME(d.from, message)
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(d.addr, d.auth, d.from, []string{message.To}, body)
+}
+
+type fileDriver struct {
+	dir string
+}
+
+// NewFileDriver writes every message as an .eml file, handy to open in a mail client during development
+func NewFileDriver(dir string) (Driver, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "funding-app-mails")
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileDriver{dir}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (d *fileDriver) Send(ctx context.Context, message Message) error {
+	body, err := buildMIME(mailFrom(), message)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.eml", time.Now().Format("20060102T150405.000000000"), message.Template, message.To)
+	path := filepath.Join(d.dir, unsafeFileChars.ReplaceAllString(name, "_"))
+
+	err = os.WriteFile(path, body, 0o644)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{"to": message.To, "subject": message.Subject, "file": path}).Info("mail written to file")
+	return nil
+}
+
+type logDriver struct{}
+
+func (d *logDriver) Send(ctx context.Context, message Message) error {
+	log.WithFields(log.Fields{"to": message.To, "subject": message.Subject, "template": message.Template}).Info(message.Text)
+	return nil
+}
+
+func mailFrom() string {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	return from
+}
+
+func buildMIME(from string, message Message) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", message.Text},
+		{"text/html; charset=UTF-8", message.HTML},
+	}
+
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		qpWriter := quotedprintable.NewWriter(partWriter)
+		_, err = qpWriter.Write([]byte(part.content))
+		if err != nil {
+			return nil, err
+		}
+
+		err = qpWriter.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package mailer
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// Message is a rendered email ready to be handed to a driver
+	Message struct {
+		To       string
+		Subject  string
+		Text     string
+		HTML     string
+		Template string
+	}
+
+	// Mail asks for a template to be rendered for a recipient
+	Mail struct {
+		To       string
+		Locale   string
+		Template string
+		Data     map[string]interface{}
+	}
+
+	Config struct {
+		AppName       string
+		AppURL        string
+		DefaultLocale string
+		Workers       int
+		QueueSize     int
+		MaxAttempts   int
+	}
+)
+
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+	SendAsync(mail Mail)
+	Start(ctx context.Context)
+}
+
+type mailer struct {
+	driver   Driver
+	renderer *renderer
+	config   Config
+	queue    chan Mail
+}
+
+func NewMailer(driver Driver, config Config) (Mailer, error) {
+	renderer, err := newRenderer(config.DefaultLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mailer{
+		driver:   driver,
+		renderer: renderer,
+		config:   config,
+		queue:    make(chan Mail, config.QueueSize),
+	}, nil
+}
+
+// Send renders and delivers the mail right away
+func (m *mailer) Send(ctx context.Context, mail Mail) error {
+	data := map[string]interface{}{
+		"AppName": m.config.AppName,
+		"AppURL":  m.config.AppURL,
+	}
+
+	for key, value := range mail.Data {
+		data[key] = value
+	}
+
+	message, err := m.renderer.render(mail.Template, mail.Locale, data)
+	if err != nil {
+		return err
+	}
+
+	message.To = mail.To
+	return m.driver.Send(ctx, message)
+}
+
+// SendAsync queues the mail for the workers so callers never wait on the mail server, a mail
+// that finds the queue full is dropped and logged right away
+func (m *mailer) SendAsync(mail Mail) {
+	select {
+	case m.queue <- mail:
+	default:
+		log.WithFields(log.Fields{"to": mail.To, "template": mail.Template}).Error("mail queue is full, mail dropped")
+	}
+}
+
+func (m *mailer) Start(ctx context.Context) {
+	for i := 0; i < m.config.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case mail := <-m.queue:
+					m.deliver(ctx, mail)
+				}
+			}
+		}()
+	}
+}
+
+func (m *mailer) deliver(ctx context.Context, mail Mail) {
+	for attempt := 1; attempt <= m.config.MaxAttempts; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := m.Send(sendCtx, mail)
+		cancel()
+
+		if err == nil {
+			return
+		}
+
+		log.WithFields(log.Fields{"template": mail.Template, "attempt": attempt}).Error(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(attempt) * 2 * time.Second):
+		}
+	}
+}
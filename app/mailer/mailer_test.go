@@ -0,0 +1,137 @@
+package mailer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMailer(t *testing.T, queueSize int) (*mailer, *MemoryDriver) {
+	t.Helper()
+
+	driver := NewMemoryDriver()
+
+	m, err := NewMailer(driver, Config{
+		AppName:       "Funding App",
+		AppURL:        "https://funding.example.com",
+		DefaultLocale: "en",
+		Workers:       1,
+		QueueSize:     queueSize,
+		MaxAttempts:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m.(*mailer), driver
+}
+
+func TestSendRendersTemplate(t *testing.T) {
+	tests := []struct {
+		locale  string
+		subject string
+	}{
+		{"en", "Welcome to Funding App"},
+		{"id", "Selamat datang di Funding App"},
+		{"fr", "Welcome to Funding App"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.locale, func(t *testing.T) {
+			m, driver := newTestMailer(t, 1)
+
+			err := m.Send(context.Background(), Mail{
+				To:       "jane@example.com",
+				Locale:   test.locale,
+				Template: TemplateWelcome,
+				Data:     map[string]interface{}{"Name": "Jane"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			messages := driver.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(messages))
+			}
+
+			message := messages[0]
+			if message.To != "jane@example.com" || message.Template != TemplateWelcome {
+				t.Errorf("message = %+v", message)
+			}
+
+			if message.Subject != test.subject {
+				t.Errorf("subject = %q, want %q", message.Subject, test.subject)
+			}
+
+			if !strings.Contains(message.Text, "Hi Jane") || !strings.Contains(message.HTML, "Jane") {
+				t.Error("body doesn't contain the template data")
+			}
+
+			if !strings.Contains(message.Text, "https://funding.example.com") {
+				t.Error("body doesn't contain the app url")
+			}
+		})
+	}
+}
+
+func TestSendRejectsUnknownTemplate(t *testing.T) {
+	m, driver := newTestMailer(t, 1)
+
+	err := m.Send(context.Background(), Mail{To: "jane@example.com", Template: "missing"})
+	if err == nil {
+		t.Fatal("Send accepted an unknown template")
+	}
+
+	if len(driver.Messages()) != 0 {
+		t.Error("a message was sent for an unknown template")
+	}
+}
+
+func TestSendAsyncDelivers(t *testing.T) {
+	m, driver := newTestMailer(t, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.Start(ctx)
+	m.SendAsync(Mail{To: "jane@example.com", Template: TemplateWelcome, Data: map[string]interface{}{"Name": "Jane"}})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(driver.Messages()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("queued mail was never delivered")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSendAsyncDropsWhenQueueIsFull(t *testing.T) {
+	// without workers nothing drains the queue
+	m, driver := newTestMailer(t, 1)
+	mail := Mail{To: "jane@example.com", Template: TemplateWelcome}
+
+	m.SendAsync(mail)
+
+	done := make(chan struct{})
+	go func() {
+		m.SendAsync(mail)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SendAsync blocked on a full queue")
+	}
+
+	if len(m.queue) != 1 {
+		t.Errorf("queue holds %d mails, want 1", len(m.queue))
+	}
+
+	if len(driver.Messages()) != 0 {
+		t.Error("a mail was sent without workers")
+	}
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryDriver keeps sent messages in memory so tests can assert on them
+type MemoryDriver struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{}
+}
+
+func (d *MemoryDriver) Send(ctx context.Context, message Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.messages = append(d.messages, message)
+	return nil
+}
+
+func (d *MemoryDriver) Messages() []Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	messages := make([]Message, len(d.messages))
+	copy(messages, d.messages)
+
+	return messages
+}
+
+func (d *MemoryDriver) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.messages = nil
+}
@@ -0,0 +1,163 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+const (
+	TemplateWelcome           = "welcome"
+	TemplateEmailVerification = "email_verification"
+	TemplatePasswordReset     = "password_reset"
+	TemplateBackingReceipt    = "backing_receipt"
+	TemplateCampaignFunded    = "campaign_funded"
+	TemplateCampaignExpired   = "campaign_expired"
+	TemplateAccountLocked     = "account_locked"
+	TemplateDataExportReady   = "data_export_ready"
+	TemplateAccountDeleted    = "account_deleted"
+
+	TemplateAccountDeletionConfirmation = "account_deletion_confirmation"
+)
+
+// subjects are localized per template, the body templates are shared between locales
+var subjects = map[string]map[string]string{
+	"en": {
+		TemplateWelcome:           "Welcome to {{.AppName}}",
+		TemplateEmailVerification: "Verify your email address",
+		TemplatePasswordReset:     "Reset your password",
+		TemplateBackingReceipt:    "Thank you for backing {{.CampaignName}}",
+		TemplateCampaignFunded:    "{{.CampaignName}} has been funded",
+		TemplateCampaignExpired:   "{{.CampaignName}} has ended",
+		TemplateAccountLocked:     "Your account has been temporarily locked",
+		TemplateDataExportReady:   "Your data export is ready",
+		TemplateAccountDeleted:    "Your {{.AppName}} account has been deleted",
+
+		TemplateAccountDeletionConfirmation: "Confirm the deletion of your {{.AppName}} account",
+	},
+	"id": {
+		TemplateWelcome:           "Selamat datang di {{.AppName}}",
+		TemplateEmailVerification: "Verifikasi alamat email kamu",
+		TemplatePasswordReset:     "Atur ulang kata sandi kamu",
+		TemplateBackingReceipt:    "Terima kasih telah mendukung {{.CampaignName}}",
+		TemplateCampaignFunded:    "{{.CampaignName}} telah terdanai",
+		TemplateCampaignExpired:   "{{.CampaignName}} telah berakhir",
+		TemplateAccountLocked:     "Akun kamu dikunci sementara",
+		TemplateDataExportReady:   "Ekspor data kamu sudah siap",
+		TemplateAccountDeleted:    "Akun {{.AppName}} kamu telah dihapus",
+
+		TemplateAccountDeletionConfirmation: "Konfirmasi penghapusan akun {{.AppName}} kamu",
+	},
+}
+
+type renderer struct {
+	defaultLocale string
+	subjects      map[string]map[string]*texttemplate.Template
+	html          map[string]*htmltemplate.Template
+	text          map[string]*texttemplate.Template
+}
+
+func newRenderer(defaultLocale string) (*renderer, error) {
+	if _, ok := subjects[defaultLocale]; !ok {
+		defaultLocale = "en"
+	}
+
+	r := &renderer{
+		defaultLocale: defaultLocale,
+		subjects:      map[string]map[string]*texttemplate.Template{},
+		html:          map[string]*htmltemplate.Template{},
+		text:          map[string]*texttemplate.Template{},
+	}
+
+	layout, err := htmltemplate.ParseFS(templateFS, "templates/layout.html")
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range subjects["en"] {
+		htmlLayout, err := layout.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		r.html[name], err = htmlLayout.ParseFS(templateFS, "templates/"+name+".html")
+		if err != nil {
+			return nil, err
+		}
+
+		r.text[name], err = texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for locale, localeSubjects := range subjects {
+		r.subjects[locale] = map[string]*texttemplate.Template{}
+
+		for name, subject := range localeSubjects {
+			r.subjects[locale][name], err = texttemplate.New(name).Parse(subject)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (r *renderer) render(name string, locale string, data map[string]interface{}) (Message, error) {
+	message := Message{Template: name}
+
+	htmlTemplate, ok := r.html[name]
+	if !ok {
+		return message, fmt.Errorf("unknown mail template %s", name)
+	}
+
+	var subject, text, html bytes.Buffer
+
+	err := r.subject(name, locale).Execute(&subject, data)
+	if err != nil {
+		return message, err
+	}
+
+	err = r.text[name].Execute(&text, data)
+	if err != nil {
+		return message, err
+	}
+
+	err = htmlTemplate.ExecuteTemplate(&html, "layout", data)
+	if err != nil {
+		return message, err
+	}
+
+	message.Subject = strings.TrimSpace(subject.String())
+	message.Text = text.String()
+	message.HTML = html.String()
+
+	return message, nil
+}
+
+func (r *renderer) subject(name string, locale string) *texttemplate.Template {
+	// accept both "id" and "id-ID"
+	locale = strings.ToLower(strings.SplitN(locale, "-", 2)[0])
+
+	if subject, ok := r.subjects[locale][name]; ok {
+		return subject
+	}
+
+	return r.subjects[r.defaultLocale][name]
+}
+
+// LocaleFromHeader picks the first language of an Accept-Language header
+func LocaleFromHeader(acceptLanguage string) string {
+	locale := strings.SplitN(acceptLanguage, ",", 2)[0]
+	locale = strings.SplitN(locale, ";", 2)[0]
+
+	return strings.TrimSpace(locale)
+}
@@ -0,0 +1,50 @@
+package media
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StartGarbageCollector runs CollectGarbage every interval until the context is done
+func StartGarbageCollector(ctx context.Context, mediaService Service, interval time.Duration, gracePeriod time.Duration, dryRun bool) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := mediaService.CollectGarbage(ctx, gracePeriod, dryRun)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+
+			log.WithFields(log.Fields{
+				"dry_run":       report.DryRun,
+				"orphans":       len(report.Orphans),
+				"deleted_media": report.DeletedMedia,
+				"deleted_files": report.DeletedFiles,
+				"failed_files":  report.FailedFiles,
+			}).Info("media garbage collection finished")
+
+			if report.DryRun {
+				for _, orphan := range report.Orphans {
+					log.WithFields(log.Fields{
+						"id":         orphan.ID,
+						"kind":       orphan.Kind,
+						"file_keys":  orphan.FileKeys,
+						"updated_at": orphan.UpdatedAt,
+					}).Info("media would be deleted")
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package media
+
+import (
+	"funding-app/app/imaging"
+	"time"
+)
+
+const (
+	KindAvatar        = "avatar"
+	KindCampaignImage = "campaign_image"
+)
+
+type Media struct {
+	ID        string
+	Hash      string
+	Kind      string
+	FileKeys  imaging.Variants
+	Variants  imaging.Variants
+	RefCount  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type GarbageReport struct {
+	DryRun       bool      `json:"dry_run"`
+	GracePeriod  string    `json:"grace_period"`
+	Orphans      []Orphan  `json:"orphans"`
+	DeletedMedia int       `json:"deleted_media"`
+	DeletedFiles int       `json:"deleted_files"`
+	FailedFiles  int       `json:"failed_files"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
+
+type Orphan struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	FileKeys  []string  `json:"file_keys"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,200 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	Save(ctx context.Context, media Media) (bool, error)
+	Acquire(ctx context.Context, hash string, kind string) (Media, error)
+	Release(ctx context.Context, ID string) (Media, error)
+	FindOrphans(ctx context.Context, before time.Time, afterID string, limit int) ([]Media, error)
+	DeleteOrphan(ctx context.Context, ID string, before time.Time) (bool, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewMediaRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+// Save inserts a new media with one reference, false means the same hash was stored concurrently
+func (r *repository) Save(ctx context.Context, media Media) (bool, error) {
+	sqlQuery := "INSERT INTO media (id, hash, kind, file_keys, variants, ref_count, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (hash, kind) DO NOTHING"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx,
+		media.ID,
+		media.Hash,
+		media.Kind,
+		media.FileKeys,
+		media.Variants,
+		media.RefCount,
+		time.Now().Format(layoutDateTime),
+		time.Now().Format(layoutDateTime),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// Acquire adds a reference to an already stored media, an empty media is returned when the hash is unknown
+func (r *repository) Acquire(ctx context.Context, hash string, kind string) (Media, error) {
+	sqlQuery := "UPDATE media SET ref_count = ref_count + 1, updated_at = $1 WHERE hash = $2 AND kind = $3 RETURNING id, hash, kind, file_keys, variants, ref_count, created_at, updated_at"
+
+	return r.queryOne(ctx, sqlQuery, time.Now().Format(layoutDateTime), hash, kind)
+}
+
+// Release drops a reference, unreferenced media is left for the garbage collector
+func (r *repository) Release(ctx context.Context, ID string) (Media, error) {
+	sqlQuery := "UPDATE media SET ref_count = ref_count - 1, updated_at = $1 WHERE id = $2 AND ref_count > 0 RETURNING id, hash, kind, file_keys, variants, ref_count, created_at, updated_at"
+
+	return r.queryOne(ctx, sqlQuery, time.Now().Format(layoutDateTime), ID)
+}
+
+// FindOrphans lists up to limit media without references that weren't touched since before,
+// ordered by id so the collector can page with afterID
+func (r *repository) FindOrphans(ctx context.Context, before time.Time, afterID string, limit int) ([]Media, error) {
+	medias := []Media{}
+
+	sqlQuery := "SELECT id, hash, kind, file_keys, variants, ref_count, created_at, updated_at FROM media WHERE ref_count = 0 AND updated_at < $1 AND id > $2 ORDER BY id LIMIT $3"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return medias, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, before.Format(layoutDateTime), afterID, limit)
+	if err != nil {
+		return medias, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		media := Media{}
+		var createdAt, updatedAt string
+
+		err := rows.Scan(
+			&media.ID,
+			&media.Hash,
+			&media.Kind,
+			&media.FileKeys,
+			&media.Variants,
+			&media.RefCount,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return medias, err
+		}
+
+		if media.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if media.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
+		}
+
+		medias = append(medias, media)
+	}
+
+	return medias, nil
+}
+
+// DeleteOrphan removes the row only when it is still unreferenced, an upload reusing it in the meantime keeps it alive
+func (r *repository) DeleteOrphan(ctx context.Context, ID string, before time.Time) (bool, error) {
+	sqlQuery := "DELETE FROM media WHERE id = $1 AND ref_count = 0 AND updated_at < $2"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, ID, before.Format(layoutDateTime))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) queryOne(ctx context.Context, sqlQuery string, args ...interface{}) (Media, error) {
+	media := Media{}
+	var createdAt, updatedAt string
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return media, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return media, err
+	}
+
+	defer rows.Close()
+
+	if rows.Next() {
+		err := rows.Scan(
+			&media.ID,
+			&media.Hash,
+			&media.Kind,
+			&media.FileKeys,
+			&media.Variants,
+			&media.RefCount,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return media, err
+		}
+	}
+
+	if createdAt != "" || updatedAt != "" {
+		if media.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if media.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
+		}
+	}
+
+	return media, nil
+}
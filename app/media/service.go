@@ -0,0 +1,206 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"funding-app/app/helper"
+	"funding-app/app/imaging"
+	"funding-app/app/storage"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Service interface {
+	Upload(ctx context.Context, kind string, file io.Reader) (Media, error)
+	Release(ctx context.Context, mediaID string) error
+	CollectGarbage(ctx context.Context, gracePeriod time.Duration, dryRun bool) (GarbageReport, error)
+}
+
+// garbageBatchSize bounds how many orphans are loaded at once
+const garbageBatchSize = 100
+
+type service struct {
+	mediaRepository Repository
+	storage         storage.Storage
+	processor       *imaging.Processor
+}
+
+func NewMediaService(mediaRepository Repository, storage storage.Storage, processor *imaging.Processor) Service {
+	return &service{mediaRepository, storage, processor}
+}
+
+// Upload stores the file once per content hash, a known hash only gains a reference
+func (s *service) Upload(ctx context.Context, kind string, file io.Reader) (Media, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return Media{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	media, err := s.mediaRepository.Acquire(ctx, hash, kind)
+	if err != nil {
+		return media, err
+	}
+
+	if media.ID != "" {
+		return media, nil
+	}
+
+	media, err = s.store(ctx, kind, hash, data)
+	if err != nil {
+		return media, err
+	}
+
+	isSaved, err := s.mediaRepository.Save(ctx, media)
+	if err != nil || !isSaved {
+		s.deleteFiles(ctx, media.FileKeys)
+	}
+
+	if err != nil {
+		return Media{}, err
+	}
+
+	if !isSaved {
+		// someone stored the same file in the meantime, use theirs
+		return s.mediaRepository.Acquire(ctx, hash, kind)
+	}
+
+	return media, nil
+}
+
+func (s *service) Release(ctx context.Context, mediaID string) error {
+	if mediaID == "" {
+		return nil
+	}
+
+	_, err := s.mediaRepository.Release(ctx, mediaID)
+	return err
+}
+
+// CollectGarbage deletes media nobody references anymore once the grace period passed,
+// in dry run it only reports what would be deleted
+func (s *service) CollectGarbage(ctx context.Context, gracePeriod time.Duration, dryRun bool) (GarbageReport, error) {
+	report := GarbageReport{
+		DryRun:      dryRun,
+		GracePeriod: gracePeriod.String(),
+		Orphans:     []Orphan{},
+		StartedAt:   time.Now(),
+	}
+
+	before := report.StartedAt.Add(-gracePeriod)
+	afterID := ""
+
+	for {
+		orphans, err := s.mediaRepository.FindOrphans(ctx, before, afterID, garbageBatchSize)
+		if err != nil {
+			return report, err
+		}
+
+		for _, media := range orphans {
+			err := s.collect(ctx, media, before, &report)
+			if err != nil {
+				return report, err
+			}
+		}
+
+		if len(orphans) < garbageBatchSize {
+			break
+		}
+
+		afterID = orphans[len(orphans)-1].ID
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// collect reports one orphan and deletes it unless this is a dry run
+func (s *service) collect(ctx context.Context, media Media, before time.Time, report *GarbageReport) error {
+	orphan := Orphan{
+		ID:        media.ID,
+		Kind:      media.Kind,
+		FileKeys:  []string{},
+		UpdatedAt: media.UpdatedAt,
+	}
+
+	for _, fileKey := range media.FileKeys {
+		orphan.FileKeys = append(orphan.FileKeys, fileKey)
+	}
+
+	report.Orphans = append(report.Orphans, orphan)
+	if report.DryRun {
+		return nil
+	}
+
+	isDeleted, err := s.mediaRepository.DeleteOrphan(ctx, media.ID, before)
+	if err != nil || !isDeleted {
+		return err
+	}
+
+	report.DeletedMedia++
+	for _, fileKey := range media.FileKeys {
+		err := s.storage.Delete(ctx, fileKey)
+		if err != nil {
+			log.Error(err)
+			report.FailedFiles++
+			continue
+		}
+
+		report.DeletedFiles++
+	}
+
+	return nil
+}
+
+func (s *service) store(ctx context.Context, kind string, hash string, data []byte) (Media, error) {
+	media := Media{
+		ID:       helper.GenerateID(),
+		Hash:     hash,
+		Kind:     kind,
+		FileKeys: imaging.Variants{},
+		Variants: imaging.Variants{},
+		RefCount: 1,
+	}
+
+	variants := imaging.CampaignImageVariants
+	folder := os.Getenv("CLOUDINARY_UPLOAD_FOLDER_CAMPAIGN_IMAGE")
+	if kind == KindAvatar {
+		variants = imaging.AvatarVariants
+		folder = os.Getenv("CLOUDINARY_UPLOAD_FOLDER_AVATAR")
+	}
+
+	processedImages, err := s.processor.Process(bytes.NewReader(data), variants)
+	if err != nil {
+		return media, err
+	}
+
+	for _, processedImage := range processedImages {
+		uploadedFile, err := s.storage.Upload(ctx, folder, bytes.NewReader(processedImage.Data))
+		if err != nil {
+			// don't leave half of the variants behind
+			s.deleteFiles(ctx, media.FileKeys)
+			return media, err
+		}
+
+		media.FileKeys[processedImage.Name] = uploadedFile.Key
+		media.Variants[processedImage.Name] = uploadedFile.URL
+	}
+
+	return media, nil
+}
+
+func (s *service) deleteFiles(ctx context.Context, fileKeys imaging.Variants) {
+	for _, fileKey := range fileKeys {
+		err := s.storage.Delete(ctx, fileKey)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package media
+
+import (
+	"context"
+	"funding-app/app/imaging"
+	"funding-app/app/key"
+	"io"
+	"sync"
+)
+
+func ImageUploadAvatarHandler(ctx context.Context, wg *sync.WaitGroup, mediaService Service, input io.Reader, fileResponse chan key.FileUploadResponse) {
+	imageUploadHandler(ctx, wg, mediaService, KindAvatar, input, fileResponse)
+}
+
+func ImageUploadCampaignImageHandler(ctx context.Context, wg *sync.WaitGroup, mediaService Service, input io.Reader, fileResponse chan key.FileUploadResponse) {
+	imageUploadHandler(ctx, wg, mediaService, KindCampaignImage, input, fileResponse)
+}
+
+// imageUploadHandler runs under the caller's context, the upload worker bounds it with UPLOAD_TIMEOUT
+func imageUploadHandler(ctx context.Context, wg *sync.WaitGroup, mediaService Service, kind string, input io.Reader, fileResponse chan key.FileUploadResponse) {
+	defer wg.Done()
+
+	uploadedMedia, err := mediaService.Upload(ctx, kind, input)
+	if err != nil {
+		fileResponse <- key.FileUploadResponse{
+			SecureURL: "",
+			Err:       err,
+		}
+		return
+	}
+
+	fileResponse <- key.FileUploadResponse{
+		MediaID:   uploadedMedia.ID,
+		SecureURL: uploadedMedia.Variants[imaging.Original],
+		Variants:  uploadedMedia.Variants,
+		Err:       nil,
+	}
+}
@@ -2,18 +2,20 @@ package middleware
 
 import (
 	"context"
-	"fmt"
+	"funding-app/app/apikey"
 	"funding-app/app/auth"
 	"funding-app/app/helper"
 	"funding-app/app/key"
 	"funding-app/app/user"
+	"net"
 	"net/http"
 	"strings"
-
-	"github.com/dgrijalva/jwt-go"
 )
 
-func AuthMiddleware(h http.Handler, authService auth.Service, userService user.Service) http.Handler {
+// AuthMiddleware accepts an access token or an API key as the bearer token. A request with an API key
+// only passes RequirePermission for the scopes of the key, so every route behind this middleware
+// has to require a permission or it would be open to every key.
+func AuthMiddleware(h http.Handler, authService auth.Service, userService user.Service, apiKeyService apikey.Service) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 
@@ -29,33 +31,66 @@ func AuthMiddleware(h http.Handler, authService auth.Service, userService user.S
 			tokenString = arrayToken[1]
 		}
 
-		token, err := authService.ValidateToken(tokenString)
+		if apikey.IsAPIKey(tokenString) {
+			apiKeyMiddleware(w, r, h, tokenString, userService, apiKeyService)
+			return
+		}
+
+		claims, err := authService.ValidateToken(tokenString)
 		if err != nil {
 			response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
 			helper.JSON(w, response, http.StatusUnauthorized)
 			return
 		}
 
-		claim, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
+		session := claims.Session()
+		if authService.IsRevoked(session) {
 			response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
 			helper.JSON(w, response, http.StatusUnauthorized)
 			return
 		}
 
-		userID := fmt.Sprintf("%s", claim["user_id"])
-
-		user, err := userService.GetUserByID(userID)
-		if err != nil {
+		user, err := userService.GetUserByID(r.Context(), claims.Subject)
+		if err != nil || user.ID == "" || user.Suspended || claims.TokenVersion != user.TokenVersion {
 			response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
 			helper.JSON(w, response, http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.Background()
-		authCtx := context.WithValue(ctx, key.CtxAuthKey{}, user)
+		// keep the request context so chi's route params and cancellation reach the handler
+		authCtx := context.WithValue(r.Context(), key.CtxAuthKey{}, user)
+		authCtx = context.WithValue(authCtx, key.CtxSessionKey{}, session)
 
 		// serve to next route
 		h.ServeHTTP(w, r.WithContext(authCtx))
 	})
 }
+
+// apiKeyMiddleware authenticates the owner of the key, a key has no session
+func apiKeyMiddleware(w http.ResponseWriter, r *http.Request, h http.Handler, plaintext string, userService user.Service, apiKeyService apikey.Service) {
+	ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ipAddress = r.RemoteAddr
+	}
+
+	apiKey, err := apiKeyService.Authenticate(plaintext, ipAddress)
+	if err != nil {
+		response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+		helper.JSON(w, response, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := userService.GetUserByID(r.Context(), apiKey.UserID)
+	if err != nil || user.ID == "" || user.Suspended {
+		response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+		helper.JSON(w, response, http.StatusUnauthorized)
+		return
+	}
+
+	authCtx := context.WithValue(r.Context(), key.CtxAuthKey{}, user)
+	authCtx = context.WithValue(authCtx, key.CtxSessionKey{}, key.Session{})
+	authCtx = context.WithValue(authCtx, key.CtxAPIKeyKey{}, key.APIKey{ID: apiKey.ID, Scopes: apiKey.Scopes})
+
+	// serve to next route
+	h.ServeHTTP(w, r.WithContext(authCtx))
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/user"
+	"net/http"
+)
+
+// RequirePermission must run after AuthMiddleware, the user needs every given permission
+// and a request with an API key also needs the matching scopes
+func RequirePermission(permissions ...user.Permission) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			currentUser, ok := r.Context().Value(key.CtxAuthKey{}).(user.User)
+			if !ok {
+				response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+				helper.JSON(w, response, http.StatusUnauthorized)
+				return
+			}
+
+			apiKey, withAPIKey := r.Context().Value(key.CtxAPIKeyKey{}).(key.APIKey)
+
+			for _, permission := range permissions {
+				if !currentUser.Can(permission) {
+					response := helper.APIResponse("Forbidden", http.StatusForbidden, "error", "missing permission "+string(permission))
+					helper.JSON(w, response, http.StatusForbidden)
+					return
+				}
+
+				if withAPIKey && !apiKey.Allows(string(permission)) {
+					response := helper.APIResponse("Forbidden", http.StatusForbidden, "error", "API key is missing scope "+string(permission))
+					helper.JSON(w, response, http.StatusForbidden)
+					return
+				}
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies reads a comma separated list of CIDRs or single addresses
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	trustedProxies := []*net.IPNet{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		trustedProxies = append(trustedProxies, network)
+	}
+
+	return trustedProxies, nil
+}
+
+// RealIP replaces RemoteAddr with the client address the trusted proxies forwarded, headers
+// sent by anyone else are ignored because the client could put any address in them
+func RealIP(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedIP(r, trustedProxies); ip != "" {
+				r.RemoteAddr = ip
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwardedIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	if !isTrusted(net.ParseIP(peer), trustedProxies) {
+		return ""
+	}
+
+	forwardedFor := r.Header.Values("X-Forwarded-For")
+	if len(forwardedFor) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+
+		return ""
+	}
+
+	// every proxy appends the address it saw, the first untrusted one from the right is the client
+	hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			return ""
+		}
+
+		if !isTrusted(ip, trustedProxies) {
+			return ip.String()
+		}
+	}
+
+	return ""
+}
+
+func isTrusted(ip net.IP, trustedProxies []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
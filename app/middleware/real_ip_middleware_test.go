@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.1, ::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{"untrusted peer keeps its address", "203.0.113.9:4000", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.9:4000"},
+		{"trusted proxy forwards the client", "10.0.0.5:4000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"spoofed hop left of the client is ignored", "10.0.0.5:4000", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chained trusted proxies are skipped", "10.0.0.5:4000", []string{"198.51.100.1, 10.0.0.7", "192.168.1.1"}, "", "198.51.100.1"},
+		{"real ip from a trusted proxy", "[::1]:4000", nil, "198.51.100.3", "198.51.100.3"},
+		{"real ip is ignored next to forwarded for", "10.0.0.5:4000", []string{"garbage"}, "198.51.100.3", "10.0.0.5:4000"},
+		{"only trusted hops", "10.0.0.5:4000", []string{"10.0.0.6"}, "", "10.0.0.5:4000"},
+		{"no headers", "10.0.0.5:4000", nil, "", "10.0.0.5:4000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+
+			for _, value := range test.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if test.realIP != "" {
+				r.Header.Set("X-Real-IP", test.realIP)
+			}
+
+			var got string
+			RealIP(trustedProxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != test.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/8,not-an-ip"); err == nil {
+		t.Error("ParseTrustedProxies accepted an invalid entry")
+	}
+}
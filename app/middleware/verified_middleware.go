@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"funding-app/app/helper"
+	"funding-app/app/key"
+	"funding-app/app/user"
+	"net/http"
+)
+
+// RequireVerifiedEmail must run after AuthMiddleware
+func RequireVerifiedEmail(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentUser, ok := r.Context().Value(key.CtxAuthKey{}).(user.User)
+		if !ok {
+			response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+			helper.JSON(w, response, http.StatusUnauthorized)
+			return
+		}
+
+		if !currentUser.EmailVerified {
+			response := helper.APIResponse("Email address is not verified", http.StatusForbidden, "error", nil)
+			helper.JSON(w, response, http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,24 @@
+package oauth
+
+import "time"
+
+// State is kept between the redirect to the provider and the callback, it ties the callback
+// to the browser that started the flow and carries the PKCE verifier and the nonce
+type State struct {
+	ID           string
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// Identity links an account at a provider to a user, the subject is the stable id at the provider
+type Identity struct {
+	Provider  string
+	Subject   string
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+}
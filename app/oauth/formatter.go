@@ -0,0 +1,28 @@
+package oauth
+
+type AuthorizationFormatter struct {
+	Provider         string `json:"provider"`
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type ProviderFormatter struct {
+	Name string `json:"name"`
+}
+
+func FormatAuthorization(provider string, authorizationURL string) AuthorizationFormatter {
+	formatter := AuthorizationFormatter{}
+	formatter.Provider = provider
+	formatter.AuthorizationURL = authorizationURL
+
+	return formatter
+}
+
+func FormatProviders(providers []string) []ProviderFormatter {
+	formatters := []ProviderFormatter{}
+
+	for _, provider := range providers {
+		formatters = append(formatters, ProviderFormatter{Name: provider})
+	}
+
+	return formatters
+}
@@ -0,0 +1,12 @@
+package oauth
+
+type (
+	CallbackInput struct {
+		Code   string `json:"code" validate:"required"`
+		State  string `json:"state" validate:"required"`
+		Locale string `json:"locale" validate:"omitempty,max=16"`
+
+		// BrowserState is the copy of the state kept in the cookie of the browser that started the login
+		BrowserState string `json:"-"`
+	}
+)
@@ -0,0 +1,355 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"funding-app/app/helper"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// clockSkew is how far the clock of a provider may be off from ours
+const clockSkew = time.Minute
+
+var (
+	ErrInvalidIDToken = errors.New("identity token from the provider is invalid")
+	ErrProviderFailed = errors.New("identity provider rejected the request")
+)
+
+type ProviderConfig struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	Issuer       string
+	AuthURL      string
+	TokenURL     string
+	JWKSURL      string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// knownProviders fill in the endpoints of well known providers, every value can still be overridden
+var knownProviders = map[string]ProviderConfig{
+	"google": {
+		Issuer:   "https://accounts.google.com",
+		AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL: "https://oauth2.googleapis.com/token",
+		JWKSURL:  "https://www.googleapis.com/oauth2/v3/certs",
+	},
+}
+
+// loadProviders reads OAUTH_PROVIDERS and the OAUTH_<NAME>_* settings of every listed provider
+func loadProviders() map[string]*provider {
+	providers := map[string]*provider{}
+
+	for _, name := range strings.Split(helper.GetEnv("OAUTH_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+		defaults := knownProviders[name]
+
+		config := ProviderConfig{
+			Name:         name,
+			ClientID:     helper.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: helper.GetEnv(prefix+"CLIENT_SECRET", ""),
+			Issuer:       helper.GetEnv(prefix+"ISSUER", defaults.Issuer),
+			AuthURL:      helper.GetEnv(prefix+"AUTH_URL", defaults.AuthURL),
+			TokenURL:     helper.GetEnv(prefix+"TOKEN_URL", defaults.TokenURL),
+			JWKSURL:      helper.GetEnv(prefix+"JWKS_URL", defaults.JWKSURL),
+			RedirectURL:  helper.GetEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(helper.GetEnv(prefix+"SCOPES", "openid email profile")),
+		}
+
+		if config.ClientID == "" || config.Issuer == "" || config.AuthURL == "" || config.TokenURL == "" || config.JWKSURL == "" || config.RedirectURL == "" {
+			log.WithField("provider", name).Warn("identity provider is not fully configured, skipping it")
+			continue
+		}
+
+		providers[name] = newProvider(config)
+	}
+
+	return providers
+}
+
+// idTokenClaims are the claims of an OpenID Connect ID token that matter for logging in
+type idTokenClaims struct {
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified flexibleBool `json:"email_verified"`
+	Name          string       `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// flexibleBool accepts true and "true", some providers send the boolean claims as strings
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case bool:
+		*b = flexibleBool(value)
+	case string:
+		*b = flexibleBool(value == "true")
+	default:
+		*b = false
+	}
+
+	return nil
+}
+
+type provider struct {
+	config ProviderConfig
+	client *http.Client
+
+	mu       sync.RWMutex
+	keys     map[string]interface{}
+	loadedAt time.Time
+}
+
+func newProvider(config ProviderConfig) *provider {
+	return &provider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   map[string]interface{}{},
+	}
+}
+
+func (p *provider) authCodeURL(state string, nonce string, codeChallenge string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.config.AuthURL, "?") {
+		separator = "&"
+	}
+
+	return p.config.AuthURL + separator + query.Encode()
+}
+
+// exchange trades the authorization code for the ID token
+func (p *provider) exchange(ctx context.Context, code string, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	body := struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		log.WithFields(log.Fields{
+			"provider":    p.config.Name,
+			"status":      resp.StatusCode,
+			"error":       body.Error,
+			"description": body.ErrorDescription,
+		}).Warn("token exchange failed")
+
+		return "", ErrProviderFailed
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the signature against the keys of the provider, then issuer, audience, expiry and nonce
+func (p *provider) verifyIDToken(ctx context.Context, rawIDToken string, nonce string) (idTokenClaims, error) {
+	claims := idTokenClaims{}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithoutClaimsValidation(),
+	)
+
+	_, err := parser.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		log.WithField("provider", p.config.Name).Warn(err)
+		return claims, ErrInvalidIDToken
+	}
+
+	now := jwt.TimeFunc()
+
+	if !claims.VerifyExpiresAt(now.Add(-clockSkew), true) || !claims.VerifyIssuedAt(now.Add(clockSkew), false) {
+		return claims, ErrInvalidIDToken
+	}
+
+	if !claims.VerifyIssuer(p.config.Issuer, true) || !claims.VerifyAudience(p.config.ClientID, true) {
+		return claims, ErrInvalidIDToken
+	}
+
+	if claims.Subject == "" || claims.Nonce == "" || claims.Nonce != nonce {
+		return claims, ErrInvalidIDToken
+	}
+
+	return claims, nil
+}
+
+// key looks up a signing key of the provider, unknown kids reload the key set once in a while
+// because providers rotate their keys
+func (p *provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.RLock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.loadedAt) > 5*time.Second
+	p.mu.RUnlock()
+
+	if !ok && stale {
+		err := p.loadKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.RLock()
+		key, ok = p.keys[kid]
+		p.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
+
+func (p *provider) loadKeys(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ErrProviderFailed
+	}
+
+	jwks := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}{}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&jwks)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]interface{}{}
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key interface{}
+
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+
+			key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			curve, ok := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}[jwk.Crv]
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if !ok || errX != nil || errY != nil {
+				continue
+			}
+
+			key = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		case "OKP":
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+
+			key = ed25519.PublicKey(x)
+		default:
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys = keys
+	p.loadedAt = time.Now()
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testClientID = "client-id"
+	testNonce    = "nonce"
+)
+
+func newTestProvider(t *testing.T, key *rsa.PrivateKey) *provider {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return newProvider(ProviderConfig{
+		Name:     "test",
+		ClientID: testClientID,
+		Issuer:   testIssuer,
+		JWKSURL:  server.URL,
+	})
+}
+
+func signIDToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims idTokenClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	validClaims := func() idTokenClaims {
+		return idTokenClaims{
+			Nonce: testNonce,
+			Email: "jane@example.com",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    testIssuer,
+				Subject:   "subject",
+				Audience:  jwt.ClaimStrings{testClientID},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    string
+		key    interface{}
+		claims func(claims *idTokenClaims)
+		valid  bool
+	}{
+		{"valid", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {}, true},
+		{"audience among others", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.Audience = jwt.ClaimStrings{"other-client", testClientID}
+		}, true},
+		{"expired within clock skew", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.ExpiresAt = jwt.NewNumericDate(now.Add(-clockSkew / 2))
+		}, true},
+		{"expired", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * clockSkew))
+		}, false},
+		{"missing expiry", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.ExpiresAt = nil
+		}, false},
+		{"issued in the future", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.IssuedAt = jwt.NewNumericDate(now.Add(2 * clockSkew))
+		}, false},
+		{"wrong issuer", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.Issuer = "https://evil.example.com"
+		}, false},
+		{"wrong audience", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.Audience = jwt.ClaimStrings{"other-client"}
+		}, false},
+		{"wrong nonce", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.Nonce = "other-nonce"
+		}, false},
+		{"missing subject", jwt.SigningMethodRS256, "key-1", key, func(claims *idTokenClaims) {
+			claims.Subject = ""
+		}, false},
+		{"signed by another key", jwt.SigningMethodRS256, "key-1", otherKey, func(claims *idTokenClaims) {}, false},
+		{"unknown key id", jwt.SigningMethodRS256, "key-2", key, func(claims *idTokenClaims) {}, false},
+		{"symmetric algorithm", jwt.SigningMethodHS256, "key-1", []byte("secret"), func(claims *idTokenClaims) {}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestProvider(t, key)
+
+			claims := validClaims()
+			test.claims(&claims)
+
+			rawIDToken := signIDToken(t, test.method, test.kid, test.key, claims)
+
+			verified, err := p.verifyIDToken(context.Background(), rawIDToken, testNonce)
+			if test.valid && err != nil {
+				t.Fatalf("verifyIDToken = %v, want no error", err)
+			}
+
+			if !test.valid && err != ErrInvalidIDToken {
+				t.Fatalf("verifyIDToken = %v, want %v", err, ErrInvalidIDToken)
+			}
+
+			if test.valid && verified.Subject != "subject" {
+				t.Errorf("subject = %q, want %q", verified.Subject, "subject")
+			}
+		})
+	}
+}
+
+func TestFlexibleBool(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`true`, true},
+		{`false`, false},
+		{`"true"`, true},
+		{`"false"`, false},
+		{`1`, false},
+		{`null`, false},
+	}
+
+	for _, test := range tests {
+		var value flexibleBool
+		if err := json.Unmarshal([]byte(test.input), &value); err != nil {
+			t.Fatalf("unmarshal %s: %v", test.input, err)
+		}
+
+		if bool(value) != test.want {
+			t.Errorf("flexibleBool(%s) = %v, want %v", test.input, value, test.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	SaveState(ctx context.Context, state State) (State, error)
+	ConsumeState(ctx context.Context, stateHash string, provider string) (State, error)
+	DeleteExpiredStates(ctx context.Context) (int, error)
+	FindIdentity(ctx context.Context, provider string, subject string) (Identity, error)
+	SaveIdentity(ctx context.Context, identity Identity) (Identity, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewOAuthRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) SaveState(ctx context.Context, state State) (State, error) {
+	sqlQuery := "INSERT INTO oauth_states (id, state_hash, provider, nonce, code_verifier, expires_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return state, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		state.ID,
+		state.StateHash,
+		state.Provider,
+		state.Nonce,
+		state.CodeVerifier,
+		state.ExpiresAt.Format(layoutDateTime),
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return state, err
+	}
+
+	state.CreatedAt = now
+	return state, nil
+}
+
+// ConsumeState deletes an unexpired state and returns it, an empty state means the callback is not valid
+func (r *repository) ConsumeState(ctx context.Context, stateHash string, provider string) (State, error) {
+	state := State{}
+	var expiresAt, createdAt string
+
+	sqlQuery := "DELETE FROM oauth_states WHERE state_hash = $1 AND provider = $2 AND expires_at > $3 RETURNING id, state_hash, provider, nonce, code_verifier, expires_at, created_at"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return state, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, stateHash, provider, time.Now().Format(layoutDateTime)).Scan(
+		&state.ID,
+		&state.StateHash,
+		&state.Provider,
+		&state.Nonce,
+		&state.CodeVerifier,
+		&expiresAt,
+		&createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return State{}, nil
+	}
+
+	if err != nil {
+		return state, err
+	}
+
+	if state.ExpiresAt, err = time.Parse(time.RFC3339, expiresAt); err != nil {
+		log.Error(err)
+	}
+
+	if state.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		log.Error(err)
+	}
+
+	return state, nil
+}
+
+func (r *repository) DeleteExpiredStates(ctx context.Context) (int, error) {
+	sqlQuery := "DELETE FROM oauth_states WHERE expires_at <= $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, time.Now().Format(layoutDateTime))
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
+
+func (r *repository) FindIdentity(ctx context.Context, provider string, subject string) (Identity, error) {
+	identity := Identity{}
+	var createdAt string
+
+	sqlQuery := "SELECT provider, subject, user_id, email, created_at FROM user_identities WHERE provider = $1 AND subject = $2"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return identity, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, provider, subject).Scan(
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email,
+		&createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return Identity{}, nil
+	}
+
+	if err != nil {
+		return identity, err
+	}
+
+	if identity.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		log.Error(err)
+	}
+
+	return identity, nil
+}
+
+func (r *repository) SaveIdentity(ctx context.Context, identity Identity) (Identity, error) {
+	sqlQuery := "INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES($1, $2, $3, $4, $5)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return identity, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		identity.Provider,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return identity, err
+	}
+
+	identity.CreatedAt = now
+	return identity, nil
+}
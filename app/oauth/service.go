@@ -0,0 +1,249 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"funding-app/app/helper"
+	"funding-app/app/user"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown identity provider")
+	ErrInvalidState     = errors.New("login request is invalid or has expired, please start again")
+	ErrEmailNotVerified = errors.New("the identity provider has not verified this email")
+)
+
+type Service interface {
+	Providers() []string
+	AuthorizationURL(providerName string) (string, string, error)
+	Callback(providerName string, input CallbackInput) (user.User, error)
+	StateTTL() time.Duration
+	Start(ctx context.Context)
+}
+
+type service struct {
+	oauthRepository Repository
+	userService     user.Service
+	providers       map[string]*provider
+	stateTTL        time.Duration
+}
+
+func NewOAuthService(oauthRepository Repository, userService user.Service) Service {
+	return &service{
+		oauthRepository: oauthRepository,
+		userService:     userService,
+		providers:       loadProviders(),
+		stateTTL:        helper.GetEnvDuration("OAUTH_STATE_TTL", 10*time.Minute),
+	}
+}
+
+func (s *service) Providers() []string {
+	names := []string{}
+
+	for name := range s.providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// AuthorizationURL starts a login, the state, nonce and PKCE verifier stay on our side until the callback.
+// The state is returned too so the browser that started the login can keep a copy of it.
+func (s *service) AuthorizationURL(providerName string) (string, string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	stateToken, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	codeVerifier, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	state := State{}
+	state.ID = helper.GenerateID()
+	state.StateHash = hashToken(stateToken)
+	state.Provider = providerName
+	state.Nonce = nonce
+	state.CodeVerifier = codeVerifier
+	state.ExpiresAt = time.Now().Add(s.stateTTL)
+
+	_, err = s.oauthRepository.SaveState(ctx, state)
+	if err != nil {
+		return "", "", err
+	}
+
+	return p.authCodeURL(stateToken, nonce, codeChallenge(codeVerifier)), stateToken, nil
+}
+
+// Callback finishes the login at the provider and returns the user it belongs to. A known identity logs
+// its user in, otherwise the account with the same verified email is linked, or a new account is created.
+func (s *service) Callback(providerName string, input CallbackInput) (user.User, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p, ok := s.providers[providerName]
+	if !ok {
+		return user.User{}, ErrUnknownProvider
+	}
+
+	// a state that didn't come from this browser was planted by someone else to log it into their account
+	if input.BrowserState == "" || subtle.ConstantTimeCompare([]byte(input.State), []byte(input.BrowserState)) != 1 {
+		return user.User{}, ErrInvalidState
+	}
+
+	// the state can only be used once, even when the rest of the callback fails
+	state, err := s.oauthRepository.ConsumeState(ctx, hashToken(input.State), providerName)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if state.ID == "" {
+		return user.User{}, ErrInvalidState
+	}
+
+	rawIDToken, err := p.exchange(ctx, input.Code, state.CodeVerifier)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	claims, err := p.verifyIDToken(ctx, rawIDToken, state.Nonce)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	identity, err := s.oauthRepository.FindIdentity(ctx, providerName, claims.Subject)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	var loggedInUser user.User
+
+	if identity.UserID != "" {
+		loggedInUser, err = s.userService.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			return loggedInUser, err
+		}
+	} else {
+		loggedInUser, err = s.link(ctx, providerName, claims, input.Locale)
+		if err != nil {
+			return loggedInUser, err
+		}
+	}
+
+	if loggedInUser.Suspended {
+		return loggedInUser, user.ErrUserSuspended
+	}
+
+	return loggedInUser, nil
+}
+
+func (s *service) StateTTL() time.Duration {
+	return s.stateTTL
+}
+
+// link only trusts emails the provider verified, otherwise anyone could claim an account by its email
+func (s *service) link(ctx context.Context, providerName string, claims idTokenClaims, locale string) (user.User, error) {
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return user.User{}, ErrEmailNotVerified
+	}
+
+	existingUser, err := s.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return existingUser, err
+	}
+
+	var linkedUser user.User
+
+	if existingUser.ID != "" {
+		linkedUser, err = s.userService.ClaimEmail(ctx, existingUser.ID)
+	} else {
+		linkedUser, err = s.userService.RegisterExternalUser(ctx, user.RegisterExternalUserInput{
+			Name:   claims.Name,
+			Email:  claims.Email,
+			Locale: locale,
+		})
+	}
+
+	if err != nil {
+		return linkedUser, err
+	}
+
+	identity := Identity{}
+	identity.Provider = providerName
+	identity.Subject = claims.Subject
+	identity.UserID = linkedUser.ID
+	identity.Email = claims.Email
+
+	_, err = s.oauthRepository.SaveIdentity(ctx, identity)
+	if err != nil {
+		return linkedUser, err
+	}
+
+	log.WithFields(log.Fields{"provider": providerName, "user_id": linkedUser.ID}).Info("linked identity to user")
+	return linkedUser, nil
+}
+
+// Start prunes logins that were started but never came back
+func (s *service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.stateTTL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := s.oauthRepository.DeleteExpiredStates(ctx)
+				if err != nil {
+					log.Error(err)
+				}
+			}
+		}
+	}()
+}
+
+func codeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCodeChallenge(t *testing.T) {
+	tests := []struct {
+		verifier  string
+		challenge string
+	}{
+		// RFC 7636 Appendix B
+		{"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+	}
+
+	for _, test := range tests {
+		if got := codeChallenge(test.verifier); got != test.challenge {
+			t.Errorf("codeChallenge(%q) = %q, want %q", test.verifier, got, test.challenge)
+		}
+	}
+}
+
+// stateRepository records whether a state was consumed
+type stateRepository struct {
+	Repository
+	consumed bool
+}
+
+func (r *stateRepository) ConsumeState(ctx context.Context, stateHash string, provider string) (State, error) {
+	r.consumed = true
+	return State{}, nil
+}
+
+func TestCallbackRequiresTheBrowserState(t *testing.T) {
+	tests := []struct {
+		name         string
+		browserState string
+	}{
+		{"no cookie", ""},
+		{"other browser", "someone-elses-state"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &stateRepository{}
+			s := &service{oauthRepository: repository, providers: map[string]*provider{"google": {}}}
+
+			_, err := s.Callback("google", CallbackInput{Code: "code", State: "state", BrowserState: test.browserState})
+			if err != ErrInvalidState {
+				t.Errorf("Callback = %v, want %v", err, ErrInvalidState)
+			}
+
+			// a forged callback must not burn the victim's real state either
+			if repository.consumed {
+				t.Error("state was consumed")
+			}
+		})
+	}
+}
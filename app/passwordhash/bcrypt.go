@@ -0,0 +1,58 @@
+package passwordhash
+
+import (
+	"fmt"
+	"funding-app/app/helper"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptAlgorithm struct {
+	cost int
+}
+
+// NewBcrypt reads the work factor from PASSWORD_BCRYPT_COST, every increment doubles the time a hash takes
+func NewBcrypt() (Algorithm, error) {
+	cost := helper.GetEnvInt("PASSWORD_BCRYPT_COST", 12)
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &bcryptAlgorithm{cost}, nil
+}
+
+func (a *bcryptAlgorithm) Name() string {
+	return "bcrypt"
+}
+
+func (a *bcryptAlgorithm) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), a.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (a *bcryptAlgorithm) Compare(hash string, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrMismatch
+	}
+
+	return err
+}
+
+func (a *bcryptAlgorithm) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (a *bcryptAlgorithm) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != a.cost
+}
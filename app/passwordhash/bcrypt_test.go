@@ -0,0 +1,100 @@
+package passwordhash
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewBcrypt(t *testing.T) {
+	tests := []struct {
+		cost  string
+		valid bool
+	}{
+		{"", true},
+		{"4", true},
+		{"31", true},
+		{"3", false},
+		{"32", false},
+	}
+
+	for _, test := range tests {
+		t.Setenv("PASSWORD_BCRYPT_COST", test.cost)
+
+		_, err := NewBcrypt()
+		if (err == nil) != test.valid {
+			t.Errorf("NewBcrypt with cost %q = %v, want valid %v", test.cost, err, test.valid)
+		}
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	algorithm := &bcryptAlgorithm{cost: bcrypt.MinCost}
+
+	hash, err := algorithm.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !algorithm.Recognizes(hash) {
+		t.Errorf("Recognizes(%q) = false", hash)
+	}
+
+	if err := algorithm.Compare(hash, "correct horse"); err != nil {
+		t.Errorf("Compare with the right password = %v", err)
+	}
+
+	if err := algorithm.Compare(hash, "wrong horse"); err != ErrMismatch {
+		t.Errorf("Compare with a wrong password = %v, want %v", err, ErrMismatch)
+	}
+}
+
+func TestBcryptRecognizes(t *testing.T) {
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{"$2a$10$abcdefghijklmnopqrstuv", true},
+		{"$2b$10$abcdefghijklmnopqrstuv", true},
+		{"$2y$10$abcdefghijklmnopqrstuv", true},
+		{"$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$aGFzaA", false},
+		{"plain", false},
+		{"", false},
+	}
+
+	algorithm := &bcryptAlgorithm{cost: bcrypt.MinCost}
+	for _, test := range tests {
+		if got := algorithm.Recognizes(test.hash); got != test.want {
+			t.Errorf("Recognizes(%q) = %v, want %v", test.hash, got, test.want)
+		}
+	}
+}
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	weak, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"same cost", string(weak), false},
+		{"other cost", string(stronger), true},
+		{"malformed", "$2a$", true},
+	}
+
+	algorithm := &bcryptAlgorithm{cost: bcrypt.MinCost}
+	for _, test := range tests {
+		if got := algorithm.NeedsRehash(test.hash); got != test.want {
+			t.Errorf("NeedsRehash %s = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
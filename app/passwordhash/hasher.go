@@ -0,0 +1,90 @@
+package passwordhash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrMismatch         = errors.New("password does not match")
+	ErrUnknownAlgorithm = errors.New("password hash uses an unknown algorithm")
+)
+
+// Algorithm is one password hashing scheme, each recognizes its own hashes so
+// several of them can verify stored passwords side by side
+type Algorithm interface {
+	Name() string
+	Hash(password string) (string, error)
+	Compare(hash string, password string) error
+	Recognizes(hash string) bool
+	NeedsRehash(hash string) bool
+}
+
+// Hasher hashes new passwords with the configured algorithm and still verifies hashes of the others
+type Hasher interface {
+	Hash(password string) (string, error)
+	Compare(hash string, password string) error
+	NeedsRehash(hash string) bool
+}
+
+type hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+// NewHasher picks the algorithm for new hashes from PASSWORD_HASH_ALGORITHM, bcrypt is the default
+func NewHasher() (Hasher, error) {
+	bcryptAlgorithm, err := NewBcrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	algorithms := []Algorithm{bcryptAlgorithm}
+
+	name := os.Getenv("PASSWORD_HASH_ALGORITHM")
+	if name == "" {
+		name = bcryptAlgorithm.Name()
+	}
+
+	for _, algorithm := range algorithms {
+		if algorithm.Name() == name {
+			return &hasher{current: algorithm, algorithms: algorithms}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported password hash algorithm %q", name)
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Compare returns ErrMismatch when the password is wrong
+func (h *hasher) Compare(hash string, password string) error {
+	algorithm := h.algorithm(hash)
+	if algorithm == nil {
+		return ErrUnknownAlgorithm
+	}
+
+	return algorithm.Compare(hash, password)
+}
+
+// NeedsRehash reports hashes made by another algorithm or with weaker settings than the current ones
+func (h *hasher) NeedsRehash(hash string) bool {
+	if !h.current.Recognizes(hash) {
+		return true
+	}
+
+	return h.current.NeedsRehash(hash)
+}
+
+func (h *hasher) algorithm(hash string) Algorithm {
+	for _, algorithm := range h.algorithms {
+		if algorithm.Recognizes(hash) {
+			return algorithm
+		}
+	}
+
+	return nil
+}
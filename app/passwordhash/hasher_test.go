@@ -0,0 +1,90 @@
+package passwordhash
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// legacyAlgorithm stands in for a scheme that still verifies old hashes but isn't used for new ones
+type legacyAlgorithm struct{}
+
+func (legacyAlgorithm) Name() string { return "legacy" }
+
+func (legacyAlgorithm) Hash(password string) (string, error) { return "$legacy$" + password, nil }
+
+func (legacyAlgorithm) Compare(hash string, password string) error {
+	if hash != "$legacy$"+password {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (legacyAlgorithm) Recognizes(hash string) bool { return strings.HasPrefix(hash, "$legacy$") }
+
+func (legacyAlgorithm) NeedsRehash(hash string) bool { return false }
+
+func TestNewHasher(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		valid     bool
+	}{
+		{"", true},
+		{"bcrypt", true},
+		{"md5", false},
+	}
+
+	for _, test := range tests {
+		t.Setenv("PASSWORD_HASH_ALGORITHM", test.algorithm)
+		t.Setenv("PASSWORD_BCRYPT_COST", "4")
+
+		_, err := NewHasher()
+		if (err == nil) != test.valid {
+			t.Errorf("NewHasher with %q = %v, want valid %v", test.algorithm, err, test.valid)
+		}
+	}
+}
+
+func TestHasher(t *testing.T) {
+	current := &bcryptAlgorithm{cost: bcrypt.MinCost}
+	h := &hasher{current: current, algorithms: []Algorithm{current, legacyAlgorithm{}}}
+
+	bcryptHash, err := h.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherCostHash, err := (&bcryptAlgorithm{cost: bcrypt.MinCost + 1}).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		hash        string
+		password    string
+		err         error
+		needsRehash bool
+	}{
+		{"current algorithm", bcryptHash, "password", nil, false},
+		{"current algorithm, wrong password", bcryptHash, "wrong", ErrMismatch, false},
+		{"current algorithm, other cost", otherCostHash, "password", nil, true},
+		{"legacy algorithm", "$legacy$password", "password", nil, true},
+		{"legacy algorithm, wrong password", "$legacy$password", "wrong", ErrMismatch, true},
+		{"unknown algorithm", "$md5$password", "password", ErrUnknownAlgorithm, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := h.Compare(test.hash, test.password); err != test.err {
+				t.Errorf("Compare = %v, want %v", err, test.err)
+			}
+
+			if got := h.NeedsRehash(test.hash); got != test.needsRehash {
+				t.Errorf("NeedsRehash = %v, want %v", got, test.needsRehash)
+			}
+		})
+	}
+}
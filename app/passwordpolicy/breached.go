@@ -0,0 +1,73 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// breachedList looks passwords up in a local copy of a k-anonymity range dump, one file per
+// 5 character SHA-1 prefix named <PREFIX>.txt with SUFFIX:COUNT lines, the layout the
+// haveibeenpwned downloader writes, so only a small file is read per check
+type breachedList struct {
+	dir      string
+	minCount int
+}
+
+func newBreachedList(dir string, minCount int) (*breachedList, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "stat", Path: dir, Err: os.ErrInvalid}
+	}
+
+	return &breachedList{dir, minCount}, nil
+}
+
+func (l *breachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(l.dir, prefix+".txt"))
+	if err != nil {
+		// a missing range means no breached password has this prefix
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		parts := strings.SplitN(line, ":", 2)
+		if !strings.EqualFold(parts[0], suffix) {
+			continue
+		}
+
+		// ranges padded with fake entries carry a count of 0
+		count := 1
+		if len(parts) == 2 {
+			count, err = strconv.Atoi(parts[1])
+			if err != nil {
+				return false, err
+			}
+		}
+
+		return count >= l.minCount, nil
+	}
+
+	return false, scanner.Err()
+}
@@ -0,0 +1,60 @@
+package passwordpolicy
+
+import "testing"
+
+// testdata/breached holds a few ranges in the downloader layout, 5BAA6 has a padding entry and
+// "password" seen 3 times in lower case, 87457 has "Tr0ub4dor&3" without a count and ABF7A has
+// "correct horse battery staple" with a malformed count
+func TestBreachedListContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		minCount int
+		want     bool
+		err      bool
+	}{
+		{"listed", "password", 1, true, false},
+		{"listed below min count", "password", 10, false, false},
+		{"listed at min count", "password", 3, true, false},
+		{"listed without count", "Tr0ub4dor&3", 1, true, false},
+		{"not in its range", "password1", 1, false, false},
+		{"range file missing", "vK7#qpL9!xWz2", 1, false, false},
+		{"malformed count", "correct horse battery staple", 1, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, err := newBreachedList("testdata/breached", test.minCount)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := list.Contains(test.password)
+			if (err != nil) != test.err {
+				t.Fatalf("Contains error = %v, want error %v", err, test.err)
+			}
+
+			if got != test.want {
+				t.Errorf("Contains(%q) = %v, want %v", test.password, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewBreachedList(t *testing.T) {
+	tests := []struct {
+		dir   string
+		valid bool
+	}{
+		{"testdata/breached", true},
+		{"testdata/missing", false},
+		{"testdata/breached/5BAA6.txt", false},
+	}
+
+	for _, test := range tests {
+		_, err := newBreachedList(test.dir, 1)
+		if (err == nil) != test.valid {
+			t.Errorf("newBreachedList(%q) = %v, want valid %v", test.dir, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package passwordpolicy
+
+import "strings"
+
+const (
+	RuleMinLength    = "min_length"
+	RuleStrength     = "strength"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+)
+
+// Violation is one rule a password failed
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// Error carries every violation at once so the user can fix the password in one go
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := []string{}
+
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+
+	return "password is not allowed: " + strings.Join(messages, ", ")
+}
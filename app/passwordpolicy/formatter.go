@@ -0,0 +1,22 @@
+package passwordpolicy
+
+type ViolationFormatter struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func FormatViolations(field string, err *Error) []ViolationFormatter {
+	formatter := []ViolationFormatter{}
+
+	for _, violation := range err.Violations {
+		violationFormatter := ViolationFormatter{}
+		violationFormatter.Field = field
+		violationFormatter.Rule = violation.Rule
+		violationFormatter.Message = violation.Message
+
+		formatter = append(formatter, violationFormatter)
+	}
+
+	return formatter
+}
@@ -0,0 +1,133 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"funding-app/app/helper"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nbutton23/zxcvbn-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// personalInfoMinLength keeps short names like "Al" from rejecting half of all passwords
+const personalInfoMinLength = 3
+
+type Policy interface {
+	// Check returns an *Error listing every violation, personal holds the name and email of the account
+	Check(password string, personal ...string) error
+}
+
+type policy struct {
+	minLength int
+	minScore  int
+	breached  *breachedList
+}
+
+// NewPolicy reads the rules from env, the breached password check is off until PASSWORD_BREACHED_DIR is set
+func NewPolicy() (Policy, error) {
+	p := &policy{
+		minLength: helper.GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		minScore:  helper.GetEnvInt("PASSWORD_MIN_SCORE", 2),
+	}
+
+	if p.minScore < 0 || p.minScore > 4 {
+		return nil, fmt.Errorf("password min score must be between 0 and 4")
+	}
+
+	if dir := helper.GetEnv("PASSWORD_BREACHED_DIR", ""); dir != "" {
+		breached, err := newBreachedList(dir, helper.GetEnvInt("PASSWORD_BREACHED_MIN_COUNT", 1))
+		if err != nil {
+			return nil, err
+		}
+
+		p.breached = breached
+	}
+
+	return p, nil
+}
+
+func (p *policy) Check(password string, personal ...string) error {
+	violations := []Violation{}
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters", p.minLength),
+		})
+	}
+
+	words := personalWords(personal)
+
+	if containsAny(password, words) {
+		violations = append(violations, Violation{
+			Rule:    RulePersonalInfo,
+			Message: "password must not contain your name or email",
+		})
+	}
+
+	if zxcvbn.PasswordStrength(password, words).Score < p.minScore {
+		violations = append(violations, Violation{
+			Rule:    RuleStrength,
+			Message: "password is too easy to guess, add more words or characters",
+		})
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			// a broken list shouldn't lock everyone out of changing their password
+			log.Error(err)
+		}
+
+		if breached {
+			violations = append(violations, Violation{
+				Rule:    RuleBreached,
+				Message: "password has appeared in a data breach, choose a different one",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &Error{violations}
+	}
+
+	return nil
+}
+
+// personalWords splits names and emails into the parts a password could borrow
+func personalWords(personal []string) []string {
+	words := []string{}
+
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		words = append(words, value)
+
+		if at := strings.LastIndex(value, "@"); at > 0 {
+			value = value[:at]
+			words = append(words, value)
+		}
+
+		words = append(words, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ' ' || r == '.' || r == '_' || r == '-' || r == '+'
+		})...)
+	}
+
+	return words
+}
+
+func containsAny(password string, words []string) bool {
+	password = strings.ToLower(password)
+
+	for _, word := range words {
+		if utf8.RuneCountInString(word) >= personalInfoMinLength && strings.Contains(password, word) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package passwordpolicy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	breached, err := newBreachedList("testdata/breached", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &policy{minLength: 8, minScore: 2, breached: breached}
+	personal := []string{"Jane Doe", "jane.doe+funding@example.com"}
+
+	tests := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{"strong", "vK7#qpL9!xWz2", nil},
+		{"passphrase", "zq8-Lm4v-Rt2w", nil},
+		{"too short", "vK7#qp", []string{RuleMinLength}},
+		{"weak", "abcdefgh", []string{RuleStrength}},
+		{"contains name", "vK7#Jane!xWz2", []string{RulePersonalInfo}},
+		{"contains email local part", "JANE.DOE+funding-2024!", []string{RulePersonalInfo}},
+		{"breached", "Tr0ub4dor&3", []string{RuleBreached}},
+		{"common and breached", "password", []string{RuleStrength, RuleBreached}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := p.Check(test.password, personal...)
+
+			if test.rules == nil {
+				if err != nil {
+					t.Fatalf("Check = %v, want no error", err)
+				}
+
+				return
+			}
+
+			policyErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Check = %v, want *Error", err)
+			}
+
+			rules := []string{}
+			for _, violation := range policyErr.Violations {
+				rules = append(rules, violation.Rule)
+			}
+
+			if !reflect.DeepEqual(rules, test.rules) {
+				t.Errorf("violated rules = %v, want %v", rules, test.rules)
+			}
+		})
+	}
+}
+
+func TestPersonalWords(t *testing.T) {
+	tests := []struct {
+		personal []string
+		want     []string
+	}{
+		{[]string{"Jane Doe"}, []string{"jane doe", "jane", "doe"}},
+		{[]string{"jane.doe+x@example.com"}, []string{"jane.doe+x@example.com", "jane.doe+x", "jane", "doe", "x"}},
+		{[]string{"", "  "}, []string{}},
+	}
+
+	for _, test := range tests {
+		if got := personalWords(test.personal); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("personalWords(%q) = %q, want %q", test.personal, got, test.want)
+		}
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		minScore string
+		dir      string
+		valid    bool
+	}{
+		{"defaults", "", "", true},
+		{"breached list", "", "testdata/breached", true},
+		{"missing breached list", "", "testdata/missing", false},
+		{"score too high", "5", "", false},
+		{"score too low", "-1", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("PASSWORD_MIN_SCORE", test.minScore)
+			t.Setenv("PASSWORD_BREACHED_DIR", test.dir)
+
+			_, err := NewPolicy()
+			if (err == nil) != test.valid {
+				t.Errorf("NewPolicy = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
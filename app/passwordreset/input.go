@@ -0,0 +1,11 @@
+package passwordreset
+
+type (
+	RequestPasswordResetInput struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	ResetPasswordInput struct {
+		Password string `json:"password" validate:"required,max=72"`
+	}
+)
@@ -0,0 +1,114 @@
+package passwordreset
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Repository interface {
+	Save(ctx context.Context, passwordReset PasswordReset) (PasswordReset, error)
+	FindUserID(ctx context.Context, tokenHash string) (string, error)
+	Consume(ctx context.Context, tokenHash string) (string, error)
+	InvalidateByUserID(ctx context.Context, userID string) error
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewPasswordResetRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, passwordReset PasswordReset) (PasswordReset, error) {
+	sqlQuery := "INSERT INTO password_resets (id, user_id, token_hash, expires_at, created_at) VALUES($1, $2, $3, $4, $5)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return passwordReset, err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx,
+		passwordReset.ID,
+		passwordReset.UserID,
+		passwordReset.TokenHash,
+		passwordReset.ExpiresAt.Format(layoutDateTime),
+		time.Now().Format(layoutDateTime),
+	)
+	if err != nil {
+		return passwordReset, err
+	}
+
+	return passwordReset, nil
+}
+
+// FindUserID returns the user of an unused and unexpired token without using it up
+func (r *repository) FindUserID(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+
+	sqlQuery := "SELECT user_id FROM password_resets WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return userID, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, tokenHash, time.Now().Format(layoutDateTime)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// Consume marks an unused and unexpired token as used and returns its user, an empty user id means the token is not valid
+func (r *repository) Consume(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+
+	sqlQuery := "UPDATE password_resets SET used_at = $1 WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1 RETURNING user_id"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return userID, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, time.Now().Format(layoutDateTime), tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+func (r *repository) InvalidateByUserID(ctx context.Context, userID string) error {
+	sqlQuery := "UPDATE password_resets SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, time.Now().Format(layoutDateTime), userID)
+	return err
+}
@@ -0,0 +1,173 @@
+package passwordreset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"funding-app/app/helper"
+	"funding-app/app/mailer"
+	"funding-app/app/user"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidToken = errors.New("reset token is invalid or has expired")
+	ErrQueueFull    = errors.New("password reset queue is full, try again later")
+)
+
+type Service interface {
+	RequestPasswordReset(input RequestPasswordResetInput) error
+	ResetPassword(token string, input ResetPasswordInput) error
+	Start(ctx context.Context)
+}
+
+type service struct {
+	passwordResetRepository Repository
+	userService             user.Service
+	mailer                  mailer.Mailer
+	tokenTTL                time.Duration
+	resetURL                string
+	queue                   chan string
+}
+
+func NewPasswordResetService(passwordResetRepository Repository, userService user.Service, mailer mailer.Mailer) Service {
+	return &service{
+		passwordResetRepository: passwordResetRepository,
+		userService:             userService,
+		mailer:                  mailer,
+		tokenTTL:                helper.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		resetURL:                helper.GetEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		queue:                   make(chan string, helper.GetEnvInt("PASSWORD_RESET_QUEUE_SIZE", 100)),
+	}
+}
+
+// RequestPasswordReset only queues the email, looking up the account and creating the link
+// happen in the worker so the response time is the same whether the account exists or not
+func (s *service) RequestPasswordReset(input RequestPasswordResetInput) error {
+	select {
+	case s.queue <- input.Email:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (s *service) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case email := <-s.queue:
+				err := s.sendResetLink(ctx, email)
+				if err != nil {
+					log.Error(err)
+				}
+			}
+		}
+	}()
+}
+
+// sendResetLink mails a new link to the account of the email, unknown emails get nothing
+func (s *service) sendResetLink(ctx context.Context, email string) error {
+	registeredUser, err := s.userService.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if registeredUser.ID == "" {
+		return nil
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	// only the newest link should work
+	err = s.passwordResetRepository.InvalidateByUserID(ctx, registeredUser.ID)
+	if err != nil {
+		return err
+	}
+
+	passwordReset := PasswordReset{}
+	passwordReset.ID = helper.GenerateID()
+	passwordReset.UserID = registeredUser.ID
+	passwordReset.TokenHash = hashToken(token)
+	passwordReset.ExpiresAt = time.Now().Add(s.tokenTTL)
+
+	_, err = s.passwordResetRepository.Save(ctx, passwordReset)
+	if err != nil {
+		return err
+	}
+
+	s.mailer.SendAsync(mailer.Mail{
+		To:       registeredUser.Email,
+		Template: mailer.TemplatePasswordReset,
+		Data: map[string]interface{}{
+			"Name":      registeredUser.Name,
+			"URL":       s.resetURL + "?token=" + url.QueryEscape(token),
+			"ExpiresIn": s.tokenTTL.String(),
+		},
+	})
+
+	return nil
+}
+
+func (s *service) ResetPassword(token string, input ResetPasswordInput) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	userID, err := s.passwordResetRepository.FindUserID(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if userID == "" {
+		return ErrInvalidToken
+	}
+
+	// a password the policy rejects must not use up the link
+	err = s.userService.CheckNewPassword(ctx, userID, input.Password)
+	if err != nil {
+		return err
+	}
+
+	userID, err = s.passwordResetRepository.Consume(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if userID == "" {
+		return ErrInvalidToken
+	}
+
+	_, err = s.userService.ResetPassword(ctx, userID, input.Password)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
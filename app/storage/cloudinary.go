@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/cloudinary/cloudinary-go"
+	"github.com/cloudinary/cloudinary-go/api/uploader"
+)
+
+type cloudinaryStorage struct {
+	cld *cloudinary.Cloudinary
+}
+
+func NewCloudinaryStorage() (Storage, error) {
+	cld, err := cloudinary.NewFromParams(
+		os.Getenv("CLOUDINARY_CLOUD_NAME"),
+		os.Getenv("CLOUDINARY_API_KEY"),
+		os.Getenv("CLOUDINARY_API_SECRET"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudinaryStorage{cld}, nil
+}
+
+func (s *cloudinaryStorage) Upload(ctx context.Context, folder string, file io.Reader) (File, error) {
+	uploadResult, err := s.cld.Upload.Upload(ctx, file, uploader.UploadParams{Folder: folder})
+	if err != nil {
+		return File{}, err
+	}
+
+	return File{Key: uploadResult.PublicID, URL: uploadResult.SecureURL}, nil
+}
+
+func (s *cloudinaryStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.cld.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: key})
+	return err
+}
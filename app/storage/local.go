@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"funding-app/app/helper"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid media key")
+)
+
+type LocalStorage struct {
+	Root    string
+	BaseURL string
+}
+
+func NewLocalStorage() (Storage, error) {
+	root := os.Getenv("MEDIA_ROOT")
+	if root == "" {
+		root = "./media"
+	}
+
+	baseURL := os.Getenv("MEDIA_BASE_URL")
+	if baseURL == "" {
+		baseURL = "/media"
+	}
+
+	err := os.MkdirAll(root, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{
+		Root:    root,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+func (s *LocalStorage) Upload(ctx context.Context, folder string, file io.Reader) (File, error) {
+	// sniff the content type so the stored file gets a proper extension
+	reader := bufio.NewReaderSize(file, 512)
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return File{}, err
+	}
+
+	ext := ""
+	exts, _ := mime.ExtensionsByType(http.DetectContentType(head))
+	if len(exts) > 0 {
+		ext = exts[0]
+	}
+
+	name := helper.GenerateID() + ext
+	key := path.Join(strings.Trim(folder, "/"), name)
+
+	fullPath, err := s.Path(key)
+	if err != nil {
+		return File{}, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(fullPath), 0o755)
+	if err != nil {
+		return File{}, err
+	}
+
+	out, err := os.Create(fullPath)
+	if err != nil {
+		return File{}, err
+	}
+
+	defer out.Close()
+
+	_, err = io.Copy(out, &contextReader{ctx, reader})
+	if err != nil {
+		os.Remove(fullPath)
+		return File{}, err
+	}
+
+	return File{Key: key, URL: s.URL(key)}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	fullPath, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(fullPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Path resolves a media key inside the root directory and refuses anything outside of it
+func (s *LocalStorage) Path(key string) (string, error) {
+	cleanKey := path.Clean("/" + key)
+	if cleanKey == "/" {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(s.Root, filepath.FromSlash(cleanKey)), nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
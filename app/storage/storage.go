@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+type File struct {
+	Key string
+	URL string
+}
+
+type Storage interface {
+	Upload(ctx context.Context, folder string, file io.Reader) (File, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorage picks the driver from STORAGE_DRIVER, cloudinary is the default
+func NewStorage() (Storage, error) {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "local":
+		return NewLocalStorage()
+	default:
+		return NewCloudinaryStorage()
+	}
+}
@@ -1 +1,15 @@
 package transaction
+
+import "time"
+
+type Transaction struct {
+	ID         string
+	CampaignID string
+	UserID     string
+	Amount     int
+	Status     string
+	Code       string
+	PaymentURL string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
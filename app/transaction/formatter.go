@@ -0,0 +1,38 @@
+package transaction
+
+import "time"
+
+type TransactionFormatter struct {
+	ID         string    `json:"id"`
+	CampaignID string    `json:"campaign_id"`
+	UserID     string    `json:"user_id"`
+	Amount     int       `json:"amount"`
+	Status     string    `json:"status"`
+	Code       string    `json:"code"`
+	PaymentURL string    `json:"payment_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func FormatTransaction(transaction Transaction) TransactionFormatter {
+	formatter := TransactionFormatter{}
+	formatter.ID = transaction.ID
+	formatter.CampaignID = transaction.CampaignID
+	formatter.UserID = transaction.UserID
+	formatter.Amount = transaction.Amount
+	formatter.Status = transaction.Status
+	formatter.Code = transaction.Code
+	formatter.PaymentURL = transaction.PaymentURL
+	formatter.CreatedAt = transaction.CreatedAt
+
+	return formatter
+}
+
+func FormatTransactions(transactions []Transaction) []TransactionFormatter {
+	formatter := []TransactionFormatter{}
+
+	for _, transaction := range transactions {
+		formatter = append(formatter, FormatTransaction(transaction))
+	}
+
+	return formatter
+}
@@ -0,0 +1,9 @@
+package transaction
+
+type (
+	GetUserTransactionsInput struct {
+		UserID  string `validate:"required"`
+		Page    int    `validate:"min=1"`
+		PerPage int    `validate:"min=1,max=100"`
+	}
+)
@@ -1 +1,76 @@
 package transaction
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	FindByUserID(ctx context.Context, userID string, limit int, offset int) ([]Transaction, int, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+func NewTransactionRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+// FindByUserID returns one page of the user's transactions, newest first, together with the total count
+func (r *repository) FindByUserID(ctx context.Context, userID string, limit int, offset int) ([]Transaction, int, error) {
+	transactions := []Transaction{}
+	total := 0
+
+	sqlQuery := "SELECT id, campaign_id, user_id, amount, status, code, COALESCE(payment_url, ''), created_at, updated_at, COUNT(*) OVER() FROM transactions WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return transactions, total, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID, limit, offset)
+	if err != nil {
+		return transactions, total, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction := Transaction{}
+		var createdAt, updatedAt string
+
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.CampaignID,
+			&transaction.UserID,
+			&transaction.Amount,
+			&transaction.Status,
+			&transaction.Code,
+			&transaction.PaymentURL,
+			&createdAt,
+			&updatedAt,
+			&total,
+		)
+		if err != nil {
+			return transactions, total, err
+		}
+
+		if transaction.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if transaction.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
+		}
+
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, total, nil
+}
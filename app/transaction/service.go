@@ -1 +1,22 @@
 package transaction
+
+import "context"
+
+type Service interface {
+	GetUserTransactions(input GetUserTransactionsInput) ([]Transaction, int, error)
+}
+
+type service struct {
+	transactionRepository Repository
+}
+
+func NewTransactionService(transactionRepository Repository) Service {
+	return &service{transactionRepository}
+}
+
+func (s *service) GetUserTransactions(input GetUserTransactionsInput) ([]Transaction, int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	return s.transactionRepository.FindByUserID(ctx, input.UserID, input.PerPage, (input.Page-1)*input.PerPage)
+}
@@ -0,0 +1,35 @@
+package twofactor
+
+import "time"
+
+// TwoFactor is the TOTP secret of a user, it only guards logins once the enrollment is confirmed
+type TwoFactor struct {
+	UserID       string
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Challenge is handed out after a correct password and traded for tokens together with a code
+type Challenge struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type Enrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte
+}
+
+type Status struct {
+	Enabled                bool
+	Pending                bool
+	RecoveryCodesRemaining int
+}
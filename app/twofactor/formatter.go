@@ -0,0 +1,63 @@
+package twofactor
+
+import (
+	"encoding/base64"
+	"time"
+)
+
+type StatusFormatter struct {
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type EnrollmentFormatter struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	QRCode string `json:"qr_code"`
+}
+
+type RecoveryCodesFormatter struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ChallengeFormatter struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+func FormatStatus(status Status) StatusFormatter {
+	formatter := StatusFormatter{}
+	formatter.Enabled = status.Enabled
+	formatter.Pending = status.Pending
+	formatter.RecoveryCodesRemaining = status.RecoveryCodesRemaining
+
+	return formatter
+}
+
+// FormatEnrollment embeds the QR code as a data URL so it can be put straight into an img tag
+func FormatEnrollment(enrollment Enrollment) EnrollmentFormatter {
+	formatter := EnrollmentFormatter{}
+	formatter.Secret = enrollment.Secret
+	formatter.URI = enrollment.URI
+	formatter.QRCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(enrollment.QRCode)
+
+	return formatter
+}
+
+func FormatRecoveryCodes(recoveryCodes []string) RecoveryCodesFormatter {
+	formatter := RecoveryCodesFormatter{}
+	formatter.RecoveryCodes = recoveryCodes
+
+	return formatter
+}
+
+func FormatChallenge(token string, ttl time.Duration) ChallengeFormatter {
+	formatter := ChallengeFormatter{}
+	formatter.TwoFactorRequired = true
+	formatter.ChallengeToken = token
+	formatter.ExpiresIn = int(ttl.Seconds())
+
+	return formatter
+}
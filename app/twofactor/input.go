@@ -0,0 +1,17 @@
+package twofactor
+
+type (
+	ConfirmInput struct {
+		Code string `json:"code" validate:"required,numeric,len=6"`
+	}
+
+	VerifyChallengeInput struct {
+		ChallengeToken string `json:"challenge_token" validate:"required"`
+		Code           string `json:"code" validate:"required,max=32"`
+	}
+
+	DisableInput struct {
+		Password string `json:"password" validate:"required"`
+		Code     string `json:"code" validate:"required,max=32"`
+	}
+)
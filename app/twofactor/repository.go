@@ -0,0 +1,381 @@
+package twofactor
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	Save(ctx context.Context, twoFactor TwoFactor) (TwoFactor, error)
+	FindByUserID(ctx context.Context, userID string) (TwoFactor, error)
+	Enable(ctx context.Context, userID string, step int64, codeHashes []string) error
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	Delete(ctx context.Context, userID string) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error)
+	SaveChallenge(ctx context.Context, challenge Challenge) (Challenge, error)
+	FindChallenge(ctx context.Context, tokenHash string) (Challenge, error)
+	FailChallenge(ctx context.Context, ID string, maxAttempts int) error
+	DeleteChallenge(ctx context.Context, ID string) (bool, error)
+	DeleteExpiredChallenges(ctx context.Context) (int, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewTwoFactorRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+// Save starts a new enrollment, an enabled secret is never replaced
+func (r *repository) Save(ctx context.Context, twoFactor TwoFactor) (TwoFactor, error) {
+	sqlQuery := `INSERT INTO two_factors (user_id, secret, enabled, last_used_step, created_at, updated_at) VALUES($1, $2, false, 0, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = EXCLUDED.updated_at
+		WHERE two_factors.enabled = false`
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return twoFactor, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	results, err := stmt.ExecContext(ctx, twoFactor.UserID, twoFactor.Secret, now.Format(layoutDateTime))
+	if err != nil {
+		return twoFactor, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return twoFactor, err
+	}
+
+	if affected == 0 {
+		return twoFactor, ErrAlreadyEnabled
+	}
+
+	twoFactor.Enabled = false
+	twoFactor.LastUsedStep = 0
+	twoFactor.CreatedAt = now
+	twoFactor.UpdatedAt = now
+	return twoFactor, nil
+}
+
+func (r *repository) FindByUserID(ctx context.Context, userID string) (TwoFactor, error) {
+	twoFactor := TwoFactor{}
+	var createdAt, updatedAt string
+
+	sqlQuery := "SELECT user_id, secret, enabled, last_used_step, created_at, updated_at FROM two_factors WHERE user_id = $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return twoFactor, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, userID).Scan(
+		&twoFactor.UserID,
+		&twoFactor.Secret,
+		&twoFactor.Enabled,
+		&twoFactor.LastUsedStep,
+		&createdAt,
+		&updatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return TwoFactor{}, nil
+	}
+
+	if err != nil {
+		return twoFactor, err
+	}
+
+	if twoFactor.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		log.Error(err)
+	}
+
+	if twoFactor.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+		log.Error(err)
+	}
+
+	return twoFactor, nil
+}
+
+// Enable confirms the enrollment and stores a fresh set of recovery codes in one go
+func (r *repository) Enable(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	now := time.Now().Format(layoutDateTime)
+
+	results, err := tx.ExecContext(ctx, "UPDATE two_factors SET enabled = true, last_used_step = $1, updated_at = $2 WHERE user_id = $3 AND enabled = false", step, now, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrAlreadyEnabled
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (code_hash, user_id, created_at) VALUES($1, $2, $3)", codeHash, userID, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseStep moves the last used time step forward, it returns false when the step was already used
+// so a code can't be replayed within its validity window
+func (r *repository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	sqlQuery := "UPDATE two_factors SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) Delete(ctx context.Context, userID string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM two_factor_challenges WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM two_factors WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	sqlQuery := "SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	defer stmt.Close()
+
+	count := 0
+
+	err = stmt.QueryRowContext(ctx, userID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *repository) ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error) {
+	sqlQuery := "UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, time.Now().Format(layoutDateTime), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) SaveChallenge(ctx context.Context, challenge Challenge) (Challenge, error) {
+	sqlQuery := "INSERT INTO two_factor_challenges (id, user_id, token_hash, attempts, expires_at, created_at) VALUES($1, $2, $3, 0, $4, $5)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return challenge, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		challenge.ID,
+		challenge.UserID,
+		challenge.TokenHash,
+		challenge.ExpiresAt.Format(layoutDateTime),
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return challenge, err
+	}
+
+	challenge.CreatedAt = now
+	return challenge, nil
+}
+
+// FindChallenge only returns challenges that haven't expired, an empty challenge means the token is not valid
+func (r *repository) FindChallenge(ctx context.Context, tokenHash string) (Challenge, error) {
+	challenge := Challenge{}
+	var expiresAt, createdAt string
+
+	sqlQuery := "SELECT id, user_id, token_hash, attempts, expires_at, created_at FROM two_factor_challenges WHERE token_hash = $1 AND expires_at > $2"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return challenge, err
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, tokenHash, time.Now().Format(layoutDateTime)).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.TokenHash,
+		&challenge.Attempts,
+		&expiresAt,
+		&createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return Challenge{}, nil
+	}
+
+	if err != nil {
+		return challenge, err
+	}
+
+	if challenge.ExpiresAt, err = time.Parse(time.RFC3339, expiresAt); err != nil {
+		log.Error(err)
+	}
+
+	if challenge.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		log.Error(err)
+	}
+
+	return challenge, nil
+}
+
+// FailChallenge counts a wrong code and throws the challenge away once it ran out of attempts
+func (r *repository) FailChallenge(ctx context.Context, ID string, maxAttempts int) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE id = $1", ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM two_factor_challenges WHERE id = $1 AND attempts >= $2", ID, maxAttempts)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteChallenge returns false when the challenge was already used by a concurrent request
+func (r *repository) DeleteChallenge(ctx context.Context, ID string) (bool, error) {
+	sqlQuery := "DELETE FROM two_factor_challenges WHERE id = $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, ID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) DeleteExpiredChallenges(ctx context.Context) (int, error) {
+	sqlQuery := "DELETE FROM two_factor_challenges WHERE expires_at <= $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	defer stmt.Close()
+
+	results, err := stmt.ExecContext(ctx, time.Now().Format(layoutDateTime))
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
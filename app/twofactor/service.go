@@ -0,0 +1,391 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"funding-app/app/helper"
+	"funding-app/app/user"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrNotEnrolled      = errors.New("two-factor enrollment has not been started")
+	ErrInvalidCode      = errors.New("invalid authentication code")
+	ErrInvalidChallenge = errors.New("login challenge is invalid or has expired")
+	ErrMissingKey       = errors.New("TWO_FACTOR_ENCRYPTION_KEY or SECRET_KEY must be set")
+)
+
+type Config struct {
+	Issuer        string
+	EncryptionKey []byte
+	ChallengeTTL  time.Duration
+	MaxAttempts   int
+}
+
+type Service interface {
+	GetStatus(userID string) (Status, error)
+	Enroll(currentUser user.User) (Enrollment, error)
+	Confirm(userID string, input ConfirmInput) ([]string, error)
+	Disable(userID string, input DisableInput) error
+	IsEnabled(userID string) (bool, error)
+	CreateChallenge(userID string) (string, error)
+	VerifyChallenge(input VerifyChallengeInput) (user.User, error)
+	ChallengeTTL() time.Duration
+	Start(ctx context.Context)
+}
+
+type service struct {
+	twoFactorRepository Repository
+	userService         user.Service
+	config              Config
+}
+
+func NewTwoFactorService(twoFactorRepository Repository, userService user.Service) (Service, error) {
+	encryptionKey := helper.GetEnv("TWO_FACTOR_ENCRYPTION_KEY", os.Getenv("SECRET_KEY"))
+	if encryptionKey == "" {
+		return nil, ErrMissingKey
+	}
+
+	return &service{
+		twoFactorRepository: twoFactorRepository,
+		userService:         userService,
+		config: Config{
+			Issuer:        helper.GetEnv("TWO_FACTOR_ISSUER", helper.GetEnv("APP_NAME", "Funding App")),
+			EncryptionKey: []byte(encryptionKey),
+			ChallengeTTL:  helper.GetEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+			MaxAttempts:   helper.GetEnvInt("TWO_FACTOR_MAX_ATTEMPTS", 5),
+		},
+	}, nil
+}
+
+func (s *service) GetStatus(userID string) (Status, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	status := Status{}
+
+	twoFactor, err := s.twoFactorRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return status, err
+	}
+
+	if twoFactor.UserID == "" {
+		return status, nil
+	}
+
+	status.Enabled = twoFactor.Enabled
+	status.Pending = !twoFactor.Enabled
+
+	if twoFactor.Enabled {
+		status.RecoveryCodesRemaining, err = s.twoFactorRepository.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return status, err
+		}
+	}
+
+	return status, nil
+}
+
+// Enroll creates a new secret, enrolling again before confirming replaces the pending secret
+func (s *service) Enroll(currentUser user.User) (Enrollment, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enrollment := Enrollment{}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return enrollment, err
+	}
+
+	encrypted, err := helper.Encrypt(s.config.EncryptionKey, []byte(secret))
+	if err != nil {
+		return enrollment, err
+	}
+
+	twoFactor := TwoFactor{}
+	twoFactor.UserID = currentUser.ID
+	twoFactor.Secret = encrypted
+
+	_, err = s.twoFactorRepository.Save(ctx, twoFactor)
+	if err != nil {
+		return enrollment, err
+	}
+
+	enrollment.Secret = secret
+	enrollment.URI = totpURI(s.config.Issuer, currentUser.Email, secret)
+
+	enrollment.QRCode, err = qrcode.Encode(enrollment.URI, qrcode.Medium, 256)
+	if err != nil {
+		return enrollment, err
+	}
+
+	return enrollment, nil
+}
+
+// Confirm enables 2FA once the user proves the authenticator app works, the recovery codes are only shown here
+func (s *service) Confirm(userID string, input ConfirmInput) ([]string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	twoFactor, err := s.twoFactorRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor.UserID == "" {
+		return nil, ErrNotEnrolled
+	}
+
+	if twoFactor.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := helper.Decrypt(s.config.EncryptionKey, twoFactor.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := validateTOTP(string(secret), input.Code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		recoveryCode, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		codeHashes = append(codeHashes, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	err = s.twoFactorRepository.Enable(ctx, userID, step, codeHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// Disable asks for the password and a code again, a stolen access token alone can't turn 2FA off
+func (s *service) Disable(userID string, input DisableInput) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := s.userService.VerifyPassword(ctx, userID, input.Password)
+	if err != nil {
+		return err
+	}
+
+	twoFactor, err := s.twoFactorRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !twoFactor.Enabled {
+		return ErrNotEnabled
+	}
+
+	err = s.verifyCode(ctx, twoFactor, input.Code)
+	if err != nil {
+		return err
+	}
+
+	return s.twoFactorRepository.Delete(ctx, userID)
+}
+
+func (s *service) IsEnabled(userID string) (bool, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	twoFactor, err := s.twoFactorRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return twoFactor.Enabled, nil
+}
+
+// CreateChallenge is called after a correct password, the returned token is only good for the second step
+func (s *service) CreateChallenge(userID string) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := Challenge{}
+	challenge.ID = helper.GenerateID()
+	challenge.UserID = userID
+	challenge.TokenHash = hashToken(token)
+	challenge.ExpiresAt = time.Now().Add(s.config.ChallengeTTL)
+
+	_, err = s.twoFactorRepository.SaveChallenge(ctx, challenge)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// VerifyChallenge finishes the login with a TOTP or recovery code, a challenge can only be used once
+func (s *service) VerifyChallenge(input VerifyChallengeInput) (user.User, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	challenge, err := s.twoFactorRepository.FindChallenge(ctx, hashToken(input.ChallengeToken))
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if challenge.ID == "" {
+		return user.User{}, ErrInvalidChallenge
+	}
+
+	twoFactor, err := s.twoFactorRepository.FindByUserID(ctx, challenge.UserID)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if !twoFactor.Enabled {
+		return user.User{}, ErrInvalidChallenge
+	}
+
+	// a lockout that started after the password step ends the challenge too
+	challengedUser, err := s.userService.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if challengedUser.Locked {
+		return user.User{}, ErrInvalidChallenge
+	}
+
+	err = s.verifyCode(ctx, twoFactor, input.Code)
+	if err != nil {
+		if err == ErrInvalidCode {
+			if err := s.twoFactorRepository.FailChallenge(ctx, challenge.ID, s.config.MaxAttempts); err != nil {
+				log.Error(err)
+			}
+
+			// new challenges come with every correct password, so wrong codes count against the account
+			if err := s.userService.RecordFailedLogin(context.Background(), challenge.UserID); err != nil {
+				log.Error(err)
+			}
+		}
+
+		return user.User{}, err
+	}
+
+	deleted, err := s.twoFactorRepository.DeleteChallenge(ctx, challenge.ID)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if !deleted {
+		return user.User{}, ErrInvalidChallenge
+	}
+
+	// the account may have been suspended since the password step
+	if challengedUser.Suspended {
+		return challengedUser, user.ErrUserSuspended
+	}
+
+	return challengedUser, nil
+}
+
+func (s *service) ChallengeTTL() time.Duration {
+	return s.config.ChallengeTTL
+}
+
+// Start prunes challenges nobody finished
+func (s *service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.config.ChallengeTTL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := s.twoFactorRepository.DeleteExpiredChallenges(ctx)
+				if err != nil {
+					log.Error(err)
+				}
+			}
+		}
+	}()
+}
+
+// verifyCode accepts either a TOTP code that wasn't used before or an unused recovery code
+func (s *service) verifyCode(ctx context.Context, twoFactor TwoFactor, code string) error {
+	secret, err := helper.Decrypt(s.config.EncryptionKey, twoFactor.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateTOTP(string(secret), code, time.Now())
+	if ok {
+		if step <= twoFactor.LastUsedStep {
+			return ErrInvalidCode
+		}
+
+		used, err := s.twoFactorRepository.UseStep(ctx, twoFactor.UserID, step)
+		if err != nil {
+			return err
+		}
+
+		if !used {
+			return ErrInvalidCode
+		}
+
+		return nil
+	}
+
+	consumed, err := s.twoFactorRepository.ConsumeRecoveryCode(ctx, twoFactor.UserID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+
+	if !consumed {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
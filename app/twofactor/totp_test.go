@@ -0,0 +1,209 @@
+package twofactor
+
+import (
+	"context"
+	"funding-app/app/helper"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B uses this ASCII secret for SHA1
+var rfcSecret = []byte("12345678901234567890")
+
+func TestTOTPCode(t *testing.T) {
+	// the appendix lists 8 digit codes, 6 digit codes are their last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		step := totpStep(time.Unix(test.unix, 0))
+		if code := totpCode(rfcSecret, step); code != test.code {
+			t.Errorf("totpCode at %d = %s, want %s", test.unix, code, test.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := secretEncoding.EncodeToString(rfcSecret)
+	now := time.Unix(1111111111, 0)
+	current := totpStep(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -1, true},
+		{"next step", 1, true},
+		{"two steps behind", -2, false},
+		{"two steps ahead", 2, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code := totpCode(rfcSecret, current+test.offset)
+
+			step, ok := validateTOTP(secret, code, now)
+			if ok != test.valid {
+				t.Fatalf("validateTOTP = %v, want %v", ok, test.valid)
+			}
+
+			if ok && step != current+test.offset {
+				t.Errorf("validateTOTP step = %d, want %d", step, current+test.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsMalformedInput(t *testing.T) {
+	secret := secretEncoding.EncodeToString(rfcSecret)
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"short code", secret, "05047"},
+		{"long code", secret, "0504711"},
+		{"invalid secret", "not base32!", "050471"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, ok := validateTOTP(test.secret, test.code, now); ok {
+				t.Error("validateTOTP accepted malformed input")
+			}
+		})
+	}
+}
+
+// stepRepository keeps the last used step in memory like the two_factors row does
+type stepRepository struct {
+	Repository
+	lastUsedStep int64
+}
+
+func (r *stepRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	if step <= r.lastUsedStep {
+		return false, nil
+	}
+
+	r.lastUsedStep = step
+	return true, nil
+}
+
+func (r *stepRepository) ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func TestVerifyCodeRejectsReplay(t *testing.T) {
+	key := []byte("test-encryption-key")
+	secret := secretEncoding.EncodeToString(rfcSecret)
+
+	encrypted, err := helper.Encrypt(key, []byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository := &stepRepository{}
+	s := &service{twoFactorRepository: repository, config: Config{EncryptionKey: key}}
+	code := totpCode(rfcSecret, totpStep(time.Now()))
+	twoFactor := TwoFactor{UserID: "user", Secret: encrypted, Enabled: true}
+
+	if err := s.verifyCode(context.Background(), twoFactor, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+
+	// a concurrent login still holding the old row is stopped by the repository
+	if err := s.verifyCode(context.Background(), twoFactor, code); err != ErrInvalidCode {
+		t.Errorf("replay with stale row = %v, want %v", err, ErrInvalidCode)
+	}
+
+	twoFactor.LastUsedStep = repository.lastUsedStep
+	if err := s.verifyCode(context.Background(), twoFactor, code); err != ErrInvalidCode {
+		t.Errorf("replay = %v, want %v", err, ErrInvalidCode)
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"abcde-fghij", "abcdefghij"},
+		{"ABCDE-FGHIJ", "abcdefghij"},
+		{"abcde fghij", "abcdefghij"},
+		{" abc-de fg-hij ", "abcdefghij"},
+		{"abcdefghij", "abcdefghij"},
+	}
+
+	for _, test := range tests {
+		if got := normalizeRecoveryCode(test.input); got != test.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(code) != 11 || code[5] != '-' {
+		t.Errorf("generateRecoveryCode = %q, want xxxxx-xxxxx", code)
+	}
+
+	if normalizeRecoveryCode(code) != code[:5]+code[6:] {
+		t.Errorf("generated code %q doesn't survive normalization", code)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("Funding App", "jane+2fa@example.com", "JBSWY3DPEHPK3PXP")
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("uri %q doesn't start with otpauth://totp/", uri)
+	}
+
+	if parsed.Path != "/Funding App:jane+2fa@example.com" {
+		t.Errorf("label = %q", parsed.Path)
+	}
+
+	want := map[string]string{
+		"secret":    "JBSWY3DPEHPK3PXP",
+		"issuer":    "Funding App",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+
+	query := parsed.Query()
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	if strings.Contains(parsed.RawQuery, "+") {
+		t.Errorf("query %q encodes spaces as +", parsed.RawQuery)
+	}
+}
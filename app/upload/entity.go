@@ -0,0 +1,43 @@
+package upload
+
+import (
+	"funding-app/app/imaging"
+	"time"
+)
+
+const (
+	KindAvatar        = "avatar"
+	KindCampaignImage = "campaign_image"
+
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+type (
+	Upload struct {
+		ID         string
+		UserID     string
+		Kind       string
+		TargetID   string
+		IsPrimary  bool
+		Status     string
+		Attempts   int
+		StagedPath string
+		URL        string
+		Variants   imaging.Variants
+		Error      string
+		CreatedAt  time.Time
+		UpdatedAt  time.Time
+	}
+
+	Result struct {
+		URL      string
+		Variants imaging.Variants
+	}
+)
+
+func (u Upload) IsDone() bool {
+	return u.Status == StatusCompleted || u.Status == StatusFailed
+}
@@ -0,0 +1,39 @@
+package upload
+
+import (
+	"funding-app/app/imaging"
+	"time"
+)
+
+type UploadFormatter struct {
+	ID        string           `json:"id"`
+	Kind      string           `json:"kind"`
+	Status    string           `json:"status"`
+	Attempts  int              `json:"attempts"`
+	URL       string           `json:"url"`
+	ImageURLs imaging.Variants `json:"image_urls"`
+	Error     string           `json:"error"`
+	StatusURL string           `json:"status_url"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+func FormatUpload(upload Upload) UploadFormatter {
+	formatter := UploadFormatter{}
+	formatter.ID = upload.ID
+	formatter.Kind = upload.Kind
+	formatter.Status = upload.Status
+	formatter.Attempts = upload.Attempts
+	formatter.URL = upload.URL
+	formatter.ImageURLs = imaging.Variants{}
+	formatter.Error = upload.Error
+	formatter.StatusURL = "/api/v1/uploads/" + upload.ID
+	formatter.CreatedAt = upload.CreatedAt
+	formatter.UpdatedAt = upload.UpdatedAt
+
+	if upload.Variants != nil {
+		formatter.ImageURLs = upload.Variants
+	}
+
+	return formatter
+}
@@ -0,0 +1,10 @@
+package upload
+
+type (
+	CreateUploadInput struct {
+		UserID    string
+		Kind      string
+		TargetID  string
+		IsPrimary bool
+	}
+)
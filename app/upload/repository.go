@@ -0,0 +1,173 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Repository interface {
+	Save(ctx context.Context, upload Upload) (Upload, error)
+	FindByID(ctx context.Context, ID string) (Upload, error)
+	FindUnfinished(ctx context.Context) ([]Upload, error)
+	Update(ctx context.Context, upload Upload) (Upload, error)
+}
+
+type repository struct {
+	DB *sql.DB
+}
+
+const (
+	layoutDateTime = "2006-01-02 15:04:05"
+)
+
+func NewUploadRepository(DB *sql.DB) Repository {
+	return &repository{DB}
+}
+
+func (r *repository) Save(ctx context.Context, upload Upload) (Upload, error) {
+	sqlQuery := "INSERT INTO uploads (id, user_id, kind, target_id, is_primary, status, attempts, staged_path, url, variants, error, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return upload, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.ExecContext(ctx,
+		upload.ID,
+		upload.UserID,
+		upload.Kind,
+		upload.TargetID,
+		upload.IsPrimary,
+		upload.Status,
+		upload.Attempts,
+		upload.StagedPath,
+		upload.URL,
+		upload.Variants,
+		upload.Error,
+		now.Format(layoutDateTime),
+		now.Format(layoutDateTime),
+	)
+	if err != nil {
+		return upload, err
+	}
+
+	upload.CreatedAt = now
+	upload.UpdatedAt = now
+
+	return upload, nil
+}
+
+func (r *repository) FindByID(ctx context.Context, ID string) (Upload, error) {
+	sqlQuery := "SELECT id, user_id, kind, target_id, is_primary, status, attempts, staged_path, url, variants, error, created_at, updated_at FROM uploads WHERE id = $1"
+
+	uploads, err := r.query(ctx, sqlQuery, ID)
+	if err != nil || len(uploads) == 0 {
+		return Upload{}, err
+	}
+
+	return uploads[0], nil
+}
+
+func (r *repository) FindUnfinished(ctx context.Context) ([]Upload, error) {
+	sqlQuery := "SELECT id, user_id, kind, target_id, is_primary, status, attempts, staged_path, url, variants, error, created_at, updated_at FROM uploads WHERE status IN ('pending', 'processing') ORDER BY created_at"
+
+	return r.query(ctx, sqlQuery)
+}
+
+func (r *repository) Update(ctx context.Context, upload Upload) (Upload, error) {
+	sqlQuery := "UPDATE uploads SET status = $1, attempts = $2, url = $3, variants = $4, error = $5, updated_at = $6 WHERE id = $7"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return upload, err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now()
+	results, err := stmt.ExecContext(ctx,
+		upload.Status,
+		upload.Attempts,
+		upload.URL,
+		upload.Variants,
+		upload.Error,
+		now.Format(layoutDateTime),
+		upload.ID,
+	)
+	if err != nil {
+		return upload, err
+	}
+
+	affected, err := results.RowsAffected()
+	if err != nil {
+		return upload, err
+	}
+
+	if int(affected) == 0 {
+		return upload, errors.New("failed when update")
+	}
+
+	upload.UpdatedAt = now
+	return upload, nil
+}
+
+func (r *repository) query(ctx context.Context, sqlQuery string, args ...interface{}) ([]Upload, error) {
+	uploads := []Upload{}
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return uploads, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return uploads, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		upload := Upload{}
+		var createdAt, updatedAt string
+
+		err := rows.Scan(
+			&upload.ID,
+			&upload.UserID,
+			&upload.Kind,
+			&upload.TargetID,
+			&upload.IsPrimary,
+			&upload.Status,
+			&upload.Attempts,
+			&upload.StagedPath,
+			&upload.URL,
+			&upload.Variants,
+			&upload.Error,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return uploads, err
+		}
+
+		if upload.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if upload.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
+		}
+
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, nil
+}
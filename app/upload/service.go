@@ -0,0 +1,187 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"funding-app/app/helper"
+	"funding-app/app/imaging"
+	"funding-app/app/jobqueue"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull      = errors.New("upload queue is full, try again later")
+	ErrUploadNotFound = errors.New("no upload found")
+)
+
+type (
+	Processor  func(ctx context.Context, upload Upload, file *os.File) (Result, error)
+	Processors map[string]Processor
+
+	Config struct {
+		Workers     int
+		QueueSize   int
+		MaxAttempts int
+		Timeout     time.Duration
+		StagingDir  string
+	}
+)
+
+type Service interface {
+	Enqueue(input CreateUploadInput, file io.Reader) (Upload, error)
+	GetUpload(ID string, userID string) (Upload, error)
+	Subscribe(ID string) (<-chan Upload, func())
+	Start(ctx context.Context)
+}
+
+type service struct {
+	uploadRepository Repository
+	processors       Processors
+	config           Config
+	runner           *jobqueue.Runner
+
+	mu          sync.Mutex
+	subscribers map[string][]chan Upload
+}
+
+func NewUploadService(uploadRepository Repository, processors Processors, config Config) (Service, error) {
+	err := os.MkdirAll(config.StagingDir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &service{
+		uploadRepository: uploadRepository,
+		processors:       processors,
+		config:           config,
+		subscribers:      map[string][]chan Upload{},
+	}
+
+	s.runner = jobqueue.NewRunner(config.Workers, config.QueueSize, s.process)
+	return s, nil
+}
+
+// Enqueue stages the file on local disk and hands it to the workers, the caller gets the pending upload right away
+func (s *service) Enqueue(input CreateUploadInput, file io.Reader) (Upload, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, ok := s.processors[input.Kind]; !ok {
+		return Upload{}, errors.New("unsupported upload kind")
+	}
+
+	upload := Upload{
+		ID:        helper.GenerateID(),
+		UserID:    input.UserID,
+		Kind:      input.Kind,
+		TargetID:  input.TargetID,
+		IsPrimary: input.IsPrimary,
+		Status:    StatusPending,
+		Variants:  imaging.Variants{},
+	}
+
+	upload.StagedPath = filepath.Join(s.config.StagingDir, upload.ID)
+
+	err := stageFile(upload.StagedPath, file)
+	if err != nil {
+		return upload, err
+	}
+
+	upload, err = s.uploadRepository.Save(ctx, upload)
+	if err != nil {
+		os.Remove(upload.StagedPath)
+		return upload, err
+	}
+
+	if !s.runner.Enqueue(upload.ID) {
+		upload.Status = StatusFailed
+		upload.Error = ErrQueueFull.Error()
+		s.uploadRepository.Update(ctx, upload)
+		os.Remove(upload.StagedPath)
+
+		return upload, ErrQueueFull
+	}
+
+	return upload, nil
+}
+
+func (s *service) GetUpload(ID string, userID string) (Upload, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upload, err := s.uploadRepository.FindByID(ctx, ID)
+	if err != nil {
+		return upload, err
+	}
+
+	// someone else's upload looks the same as a missing one
+	if upload.ID == "" || upload.UserID != userID {
+		return Upload{}, ErrUploadNotFound
+	}
+
+	return upload, nil
+}
+
+// Subscribe receives every status change of an upload until the returned func is called
+func (s *service) Subscribe(ID string) (<-chan Upload, func()) {
+	ch := make(chan Upload, 8)
+
+	s.mu.Lock()
+	s.subscribers[ID] = append(s.subscribers[ID], ch)
+	s.mu.Unlock()
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		subscribers := s.subscribers[ID]
+		for i, subscriber := range subscribers {
+			if subscriber == ch {
+				s.subscribers[ID] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+
+		if len(s.subscribers[ID]) == 0 {
+			delete(s.subscribers, ID)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (s *service) publish(upload Upload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscriber := range s.subscribers[upload.ID] {
+		// a slow subscriber only misses intermediate states, it can always poll
+		select {
+		case subscriber <- upload:
+		default:
+		}
+	}
+}
+
+func stageFile(path string, file io.Reader) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, file)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
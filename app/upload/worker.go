@@ -0,0 +1,98 @@
+package upload
+
+import (
+	"context"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Start requeues uploads left over from a previous run and spawns the worker pool
+func (s *service) Start(ctx context.Context) {
+	unfinished, err := s.uploadRepository.FindUnfinished(ctx)
+	if err != nil {
+		log.Error(err)
+	}
+
+	unfinishedIDs := []string{}
+	for _, upload := range unfinished {
+		if _, err := os.Stat(upload.StagedPath); err != nil {
+			upload.Status = StatusFailed
+			upload.Error = "staged file is gone"
+			s.save(ctx, upload)
+			continue
+		}
+
+		unfinishedIDs = append(unfinishedIDs, upload.ID)
+	}
+
+	s.runner.Start(ctx, unfinishedIDs)
+}
+
+func (s *service) process(ctx context.Context, ID string) {
+	upload, err := s.uploadRepository.FindByID(ctx, ID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if upload.ID == "" || upload.IsDone() {
+		return
+	}
+
+	upload.Attempts++
+	upload.Status = StatusProcessing
+	upload = s.save(ctx, upload)
+
+	result, err := s.run(ctx, upload)
+	if err == nil {
+		upload.Status = StatusCompleted
+		upload.URL = result.URL
+		upload.Variants = result.Variants
+		upload.Error = ""
+		s.save(ctx, upload)
+
+		os.Remove(upload.StagedPath)
+		return
+	}
+
+	log.WithFields(log.Fields{"upload_id": upload.ID, "attempt": upload.Attempts}).Error(err)
+	upload.Error = err.Error()
+
+	if upload.Attempts >= s.config.MaxAttempts {
+		upload.Status = StatusFailed
+		s.save(ctx, upload)
+
+		os.Remove(upload.StagedPath)
+		return
+	}
+
+	upload.Status = StatusPending
+	s.save(ctx, upload)
+
+	s.runner.Retry(ctx, upload.ID, upload.Attempts)
+}
+
+func (s *service) run(ctx context.Context, upload Upload) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	file, err := os.Open(upload.StagedPath)
+	if err != nil {
+		return Result{}, err
+	}
+
+	defer file.Close()
+
+	return s.processors[upload.Kind](ctx, upload, file)
+}
+
+func (s *service) save(ctx context.Context, upload Upload) Upload {
+	updatedUpload, err := s.uploadRepository.Update(ctx, upload)
+	if err != nil {
+		log.Error(err)
+	}
+
+	s.publish(updatedUpload)
+	return updatedUpload
+}
@@ -1,15 +1,26 @@
 package user
 
-import "time"
+import (
+	"funding-app/app/imaging"
+	"time"
+)
 
 type User struct {
-	ID             string    `json:"id"`
-	Name           string    `json:"name"`
-	Occupation     string    `json:"occupation"`
-	Email          string    `json:"email"`
-	PasswordHash   string    `json:"password_hash"`
-	AvatarFileName string    `json:"avatar_file_name"`
-	Role           string    `json:"role"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             string           `json:"id"`
+	Name           string           `json:"name"`
+	Occupation     string           `json:"occupation"`
+	Email          string           `json:"email"`
+	EmailVerified  bool             `json:"email_verified"`
+	PasswordHash   string           `json:"password_hash"`
+	AvatarMediaID  string           `json:"avatar_media_id"`
+	AvatarFileName string           `json:"avatar_file_name"`
+	AvatarVariants imaging.Variants `json:"avatar_variants"`
+	Role           string           `json:"role"`
+	TokenVersion   int              `json:"token_version"`
+	Suspended      bool             `json:"suspended"`
+	SuspendReason  string           `json:"suspend_reason"`
+	FailedLogins   int              `json:"failed_logins"`
+	Locked         bool             `json:"locked"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
 }
@@ -1,14 +1,61 @@
 package user
 
-import "funding-app/app/key"
+import (
+	"funding-app/app/imaging"
+	"funding-app/app/key"
+	"time"
+)
 
 type UserFormatter struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	Occupation   string `json:"occupation"`
-	Email        string `json:"email"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	ID            string           `json:"id"`
+	Name          string           `json:"name"`
+	Occupation    string           `json:"occupation"`
+	Email         string           `json:"email"`
+	EmailVerified bool             `json:"email_verified"`
+	AvatarURLs    imaging.Variants `json:"avatar_urls"`
+	AccessToken   string           `json:"access_token"`
+	RefreshToken  string           `json:"refresh_token"`
+}
+
+type ProfileFormatter struct {
+	ID            string           `json:"id"`
+	Name          string           `json:"name"`
+	Occupation    string           `json:"occupation"`
+	Email         string           `json:"email"`
+	EmailVerified bool             `json:"email_verified"`
+	Role          string           `json:"role"`
+	AvatarURL     string           `json:"avatar_url"`
+	AvatarURLs    imaging.Variants `json:"avatar_urls"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+type AdminUserFormatter struct {
+	ProfileFormatter
+	Suspended     bool   `json:"suspended"`
+	SuspendReason string `json:"suspend_reason"`
+	Locked        bool   `json:"locked"`
+	FailedLogins  int    `json:"failed_logins"`
+}
+
+func FormatProfile(user User) ProfileFormatter {
+	formatter := ProfileFormatter{}
+	formatter.ID = user.ID
+	formatter.Name = user.Name
+	formatter.Occupation = user.Occupation
+	formatter.Email = user.Email
+	formatter.EmailVerified = user.EmailVerified
+	formatter.Role = user.Role
+	formatter.AvatarURL = user.AvatarFileName
+	formatter.AvatarURLs = imaging.Variants{}
+	formatter.CreatedAt = user.CreatedAt
+	formatter.UpdatedAt = user.UpdatedAt
+
+	if user.AvatarVariants != nil {
+		formatter.AvatarURLs = user.AvatarVariants
+	}
+
+	return formatter
 }
 
 func FormatUser(user User, token key.Token) UserFormatter {
@@ -17,8 +64,31 @@ func FormatUser(user User, token key.Token) UserFormatter {
 	formatter.Name = user.Name
 	formatter.Occupation = user.Occupation
 	formatter.Email = user.Email
+	formatter.EmailVerified = user.EmailVerified
+	formatter.AvatarURLs = user.AvatarVariants
 	formatter.AccessToken = token.AccessToken
 	formatter.RefreshToken = token.RefreshToken
 
 	return formatter
 }
+
+func FormatAdminUser(user User) AdminUserFormatter {
+	formatter := AdminUserFormatter{}
+	formatter.ProfileFormatter = FormatProfile(user)
+	formatter.Suspended = user.Suspended
+	formatter.SuspendReason = user.SuspendReason
+	formatter.Locked = user.Locked
+	formatter.FailedLogins = user.FailedLogins
+
+	return formatter
+}
+
+func FormatAdminUsers(users []User) []AdminUserFormatter {
+	formatter := []AdminUserFormatter{}
+
+	for _, user := range users {
+		formatter = append(formatter, FormatAdminUser(user))
+	}
+
+	return formatter
+}
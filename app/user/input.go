@@ -5,7 +5,15 @@ type (
 		Name       string `json:"name" validate:"required"`
 		Occupation string `json:"occupation" validate:"required"`
 		Email      string `json:"email" validate:"required,email"`
-		Password   string `json:"password" validate:"required"`
+		Password   string `json:"password" validate:"required,max=72"`
+		Locale     string `json:"locale" validate:"omitempty,max=16"`
+	}
+
+	// RegisterExternalUserInput comes from an identity provider that already verified the email
+	RegisterExternalUserInput struct {
+		Name   string
+		Email  string
+		Locale string
 	}
 
 	LoginUserInput struct {
@@ -16,4 +24,31 @@ type (
 	CheckEmailInput struct {
 		Email string `json:"email" validate:"required,email"`
 	}
+
+	ChangePasswordInput struct {
+		CurrentPassword string `json:"current_password" validate:"required"`
+		NewPassword     string `json:"new_password" validate:"required,max=72,nefield=CurrentPassword"`
+	}
+
+	UpdateProfileInput struct {
+		Name       *string `json:"name" validate:"omitempty,min=1,max=100"`
+		Occupation *string `json:"occupation" validate:"omitempty,min=1,max=100"`
+		Email      *string `json:"email" validate:"omitempty,email"`
+	}
+
+	SearchUsersInput struct {
+		Query     string
+		Role      string `validate:"omitempty,oneof=user creator moderator admin"`
+		Suspended string `validate:"omitempty,oneof=true false"`
+		Page      int    `validate:"min=1"`
+		PerPage   int    `validate:"min=1,max=100"`
+	}
+
+	SuspendUserInput struct {
+		Reason string `json:"reason" validate:"required,max=500"`
+	}
+
+	ChangeRoleInput struct {
+		Role string `json:"role" validate:"required,oneof=user creator moderator admin"`
+	}
 )
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"funding-app/app/imaging"
 	"time"
 
+	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -13,7 +15,20 @@ type Repository interface {
 	Save(ctx context.Context, user User) (User, error)
 	FindByID(ctx context.Context, userID string) (User, error)
 	FindByEmail(ctx context.Context, email string) (User, error)
-	Update(ctx context.Context, user User) (User, error)
+	UpdateProfile(ctx context.Context, user User) (User, error)
+	UpdateAvatar(ctx context.Context, ID string, mediaID string, fileName string, variants imaging.Variants) (string, error)
+	UpdatePassword(ctx context.Context, ID string, passwordHash string) (User, error)
+	MarkEmailVerified(ctx context.Context, ID string, email string) (User, error)
+	ClaimEmail(ctx context.Context, ID string) (User, error)
+	Anonymize(ctx context.Context, user User) (string, error)
+	Suspend(ctx context.Context, ID string, reason string) (User, error)
+	Unsuspend(ctx context.Context, ID string) (User, error)
+	UpdateRole(ctx context.Context, ID string, role string) (User, error)
+	Search(ctx context.Context, input SearchUsersInput) ([]User, int, error)
+	RecordFailedLogin(ctx context.Context, ID string, maxFailures int, lockedUntil time.Time) (bool, error)
+	ResetFailedLogins(ctx context.Context, ID string) error
+	Unlock(ctx context.Context, ID string) error
+	UpdatePasswordHash(ctx context.Context, ID string, oldHash string, newHash string) error
 }
 
 type repository struct {
@@ -25,11 +40,12 @@ func NewUserRepository(DB *sql.DB) Repository {
 }
 
 const (
-	layoutDateTime = "2006-01-02 15:04:05"
+	layoutDateTime  = "2006-01-02 15:04:05"
+	uniqueViolation = "23505"
 )
 
 func (r *repository) Save(ctx context.Context, user User) (User, error) {
-	sqlQuery := "INSERT INTO users (id, name, occupation, email, password_hash, avatar_file_name, role, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+	sqlQuery := "INSERT INTO users (id, name, occupation, email, email_verified, password_hash, avatar_media_id, avatar_file_name, avatar_variants, role, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12)"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
@@ -40,8 +56,11 @@ func (r *repository) Save(ctx context.Context, user User) (User, error) {
 		user.Name,
 		user.Occupation,
 		user.Email,
+		user.EmailVerified,
 		user.PasswordHash,
+		user.AvatarMediaID,
 		user.AvatarFileName,
+		user.AvatarVariants,
 		user.Role,
 		time.Now().Format(layoutDateTime),
 		time.Now().Format(layoutDateTime))
@@ -57,14 +76,14 @@ func (r *repository) FindByID(ctx context.Context, userID string) (User, error)
 	user := User{}
 	var createdAt, updatedAt string
 
-	sqlQuery := "SELECT id, name, occupation, email, password_hash, avatar_file_name, role, created_at, updated_at FROM users WHERE id = $1"
+	sqlQuery := "SELECT id, name, occupation, email, email_verified, password_hash, COALESCE(avatar_media_id, ''), avatar_file_name, avatar_variants, role, token_version, suspended_at IS NOT NULL, suspend_reason, failed_logins, COALESCE(locked_until > $2, false), created_at, updated_at FROM users WHERE id = $1"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
 		return user, err
 	}
 
-	rows, err := stmt.QueryContext(ctx, userID)
+	rows, err := stmt.QueryContext(ctx, userID, time.Now().Format(layoutDateTime))
 	if err != nil {
 		return user, err
 	}
@@ -77,9 +96,17 @@ func (r *repository) FindByID(ctx context.Context, userID string) (User, error)
 			&user.Name,
 			&user.Occupation,
 			&user.Email,
+			&user.EmailVerified,
 			&user.PasswordHash,
+			&user.AvatarMediaID,
 			&user.AvatarFileName,
+			&user.AvatarVariants,
 			&user.Role,
+			&user.TokenVersion,
+			&user.Suspended,
+			&user.SuspendReason,
+			&user.FailedLogins,
+			&user.Locked,
 			&createdAt,
 			&updatedAt,
 		)
@@ -105,14 +132,14 @@ func (r *repository) FindByEmail(ctx context.Context, email string) (User, error
 	user := User{}
 	var createdAt, updatedAt string
 
-	sqlQuery := "SELECT id, name, occupation, email, password_hash, avatar_file_name, role, created_at, updated_at FROM users WHERE email = $1"
+	sqlQuery := "SELECT id, name, occupation, email, email_verified, password_hash, COALESCE(avatar_media_id, ''), avatar_file_name, avatar_variants, role, token_version, suspended_at IS NOT NULL, suspend_reason, failed_logins, COALESCE(locked_until > $2, false), created_at, updated_at FROM users WHERE email = $1"
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
 		return user, err
 	}
 
-	rows, err := stmt.QueryContext(ctx, email)
+	rows, err := stmt.QueryContext(ctx, email, time.Now().Format(layoutDateTime))
 	if err != nil {
 		return user, err
 	}
@@ -125,9 +152,17 @@ func (r *repository) FindByEmail(ctx context.Context, email string) (User, error
 			&user.Name,
 			&user.Occupation,
 			&user.Email,
+			&user.EmailVerified,
 			&user.PasswordHash,
+			&user.AvatarMediaID,
 			&user.AvatarFileName,
+			&user.AvatarVariants,
 			&user.Role,
+			&user.TokenVersion,
+			&user.Suspended,
+			&user.SuspendReason,
+			&user.FailedLogins,
+			&user.Locked,
 			&createdAt,
 			&updatedAt,
 		)
@@ -149,32 +184,287 @@ func (r *repository) FindByEmail(ctx context.Context, email string) (User, error
 	return user, nil
 }
 
-func (r *repository) Update(ctx context.Context, user User) (User, error) {
-	sqlQuery := "UPDATE users SET avatar_file_name = $1 WHERE id = $2"
+// UpdateProfile writes only the profile columns, a full row write would overwrite columns
+// changed concurrently by other requests with the values read before
+func (r *repository) UpdateProfile(ctx context.Context, user User) (User, error) {
+	sqlQuery := "UPDATE users SET name = $1, occupation = $2, email = $3, email_verified = $4, updated_at = $5 WHERE id = $6"
+
+	return r.updateAndFind(ctx, user.ID, sqlQuery,
+		user.Name,
+		user.Occupation,
+		user.Email,
+		user.EmailVerified,
+		time.Now().Format(layoutDateTime),
+		user.ID,
+	)
+}
+
+// UpdateAvatar swaps the avatar and returns the media it replaced, read in the same statement so
+// two uploads finishing together can't both release the same old avatar
+func (r *repository) UpdateAvatar(ctx context.Context, ID string, mediaID string, fileName string, variants imaging.Variants) (string, error) {
+	sqlQuery := `UPDATE users SET avatar_media_id = NULLIF($1, ''), avatar_file_name = $2, avatar_variants = $3, updated_at = $4
+		FROM (SELECT id, avatar_media_id FROM users WHERE id = $5 FOR UPDATE) previous
+		WHERE users.id = previous.id
+		RETURNING COALESCE(previous.avatar_media_id, '')`
 
 	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
-		return user, err
+		return "", err
+	}
+
+	defer stmt.Close()
+
+	var previousMediaID string
+
+	err = stmt.QueryRowContext(ctx, mediaID, fileName, variants, time.Now().Format(layoutDateTime), ID).Scan(&previousMediaID)
+	if err == sql.ErrNoRows {
+		return "", errors.New("failed when update")
 	}
 
-	results, err := stmt.ExecContext(ctx, user.AvatarFileName, user.ID)
 	if err != nil {
-		return user, err
+		return "", err
 	}
 
-	affected, err := results.RowsAffected()
+	return previousMediaID, nil
+}
+
+// UpdatePassword bumps the token version in the same statement so every token issued before stops working
+func (r *repository) UpdatePassword(ctx context.Context, ID string, passwordHash string) (User, error) {
+	sqlQuery := "UPDATE users SET password_hash = $1, token_version = token_version + 1, updated_at = $2 WHERE id = $3"
+
+	return r.updateAndFind(ctx, ID, sqlQuery, passwordHash, time.Now().Format(layoutDateTime), ID)
+}
+
+// MarkEmailVerified only verifies the address the user still has
+func (r *repository) MarkEmailVerified(ctx context.Context, ID string, email string) (User, error) {
+	sqlQuery := "UPDATE users SET email_verified = TRUE, updated_at = $1 WHERE id = $2 AND LOWER(email) = LOWER($3)"
+
+	return r.updateAndFind(ctx, ID, sqlQuery, time.Now().Format(layoutDateTime), ID, email)
+}
+
+func (r *repository) UpdateRole(ctx context.Context, ID string, role string) (User, error) {
+	sqlQuery := "UPDATE users SET role = $1, updated_at = $2 WHERE id = $3"
+
+	return r.updateAndFind(ctx, ID, sqlQuery, role, time.Now().Format(layoutDateTime), ID)
+}
+
+// ClaimEmail verifies the email and drops the password of an account whose email was unverified
+func (r *repository) ClaimEmail(ctx context.Context, ID string) (User, error) {
+	sqlQuery := "UPDATE users SET email_verified = TRUE, password_hash = '', token_version = token_version + 1, updated_at = $1 WHERE id = $2 AND NOT email_verified"
+
+	return r.updateAndFind(ctx, ID, sqlQuery, time.Now().Format(layoutDateTime), ID)
+}
+
+// Anonymize replaces the personal data of the user and returns the avatar media it dropped
+func (r *repository) Anonymize(ctx context.Context, user User) (string, error) {
+	sqlQuery := `UPDATE users SET name = $1, occupation = '', email = $2, email_verified = FALSE, password_hash = '',
+			avatar_media_id = NULL, avatar_file_name = '', avatar_variants = '{}', role = $3, token_version = token_version + 1, updated_at = $4
+		FROM (SELECT id, avatar_media_id FROM users WHERE id = $5 FOR UPDATE) previous
+		WHERE users.id = previous.id
+		RETURNING COALESCE(previous.avatar_media_id, '')`
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
 	if err != nil {
-		return user, err
+		return "", err
+	}
+
+	defer stmt.Close()
+
+	var previousMediaID string
+
+	err = stmt.QueryRowContext(ctx, user.Name, user.Email, user.Role, time.Now().Format(layoutDateTime), user.ID).Scan(&previousMediaID)
+	if err == sql.ErrNoRows {
+		return "", errors.New("failed when update")
 	}
 
-	if int(affected) > 0 {
-		detailedUser, err := r.FindByID(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return previousMediaID, nil
+}
+
+// updateAndFind runs a narrow update of one user and reads the row back
+func (r *repository) updateAndFind(ctx context.Context, ID string, sqlQuery string, args ...interface{}) (User, error) {
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return User{}, err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, args...)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+			return User{}, ErrEmailAlreadyUsed
+		}
+
+		return User{}, err
+	}
+
+	updatedUser, err := r.FindByID(ctx, ID)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	if updatedUser.ID == "" {
+		return updatedUser, errors.New("failed when update")
+	}
+
+	return updatedUser, nil
+}
+
+// Suspend bumps the token version with the suspension so the user is logged out everywhere
+func (r *repository) Suspend(ctx context.Context, ID string, reason string) (User, error) {
+	sqlQuery := "UPDATE users SET suspended_at = COALESCE(suspended_at, $1::timestamp), suspend_reason = $2, token_version = token_version + 1, updated_at = $1 WHERE id = $3"
+
+	return r.updateAndFind(ctx, ID, sqlQuery, time.Now().Format(layoutDateTime), reason, ID)
+}
+
+func (r *repository) Unsuspend(ctx context.Context, ID string) (User, error) {
+	sqlQuery := "UPDATE users SET suspended_at = NULL, suspend_reason = '', updated_at = $1 WHERE id = $2"
+
+	return r.updateAndFind(ctx, ID, sqlQuery, time.Now().Format(layoutDateTime), ID)
+}
+
+// Search returns one page of users matching the filters together with the total number of matches
+func (r *repository) Search(ctx context.Context, input SearchUsersInput) ([]User, int, error) {
+	users := []User{}
+	total := 0
+
+	sqlQuery := `SELECT id, name, occupation, email, email_verified, password_hash, COALESCE(avatar_media_id, ''), avatar_file_name, avatar_variants, role, token_version, suspended_at IS NOT NULL, suspend_reason, failed_logins, COALESCE(locked_until > $6, false), created_at, updated_at, COUNT(*) OVER()
+		FROM users
+		WHERE ($1 = '' OR name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%')
+			AND ($2 = '' OR role = $2)
+			AND ($3 = '' OR (suspended_at IS NOT NULL) = ($3 = 'true'))
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5`
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return users, total, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, input.Query, input.Role, input.Suspended, input.PerPage, (input.Page-1)*input.PerPage, time.Now().Format(layoutDateTime))
+	if err != nil {
+		return users, total, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		user := User{}
+		var createdAt, updatedAt string
+
+		err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Occupation,
+			&user.Email,
+			&user.EmailVerified,
+			&user.PasswordHash,
+			&user.AvatarMediaID,
+			&user.AvatarFileName,
+			&user.AvatarVariants,
+			&user.Role,
+			&user.TokenVersion,
+			&user.Suspended,
+			&user.SuspendReason,
+			&user.FailedLogins,
+			&user.Locked,
+			&createdAt,
+			&updatedAt,
+			&total,
+		)
 		if err != nil {
-			return user, err
+			return users, total, err
+		}
+
+		if user.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			log.Error(err)
+		}
+
+		if user.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			log.Error(err)
 		}
 
-		return detailedUser, nil
+		users = append(users, user)
 	}
 
-	return user, errors.New("failed when update")
+	return users, total, nil
+}
+
+// RecordFailedLogin counts the failure and locks the account once it reaches maxFailures,
+// it returns true only for the failure that caused the lock
+func (r *repository) RecordFailedLogin(ctx context.Context, ID string, maxFailures int, lockedUntil time.Time) (bool, error) {
+	sqlQuery := `UPDATE users SET
+			failed_logins = CASE WHEN failed_logins + 1 >= $1 THEN 0 ELSE failed_logins + 1 END,
+			locked_until = CASE WHEN failed_logins + 1 >= $1 THEN $2::timestamp ELSE locked_until END
+		WHERE id = $3
+		RETURNING locked_until IS NOT DISTINCT FROM $2::timestamp`
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	locked := false
+
+	err = stmt.QueryRowContext(ctx, maxFailures, lockedUntil.Format(layoutDateTime), ID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return locked, nil
+}
+
+func (r *repository) ResetFailedLogins(ctx context.Context, ID string) error {
+	sqlQuery := "UPDATE users SET failed_logins = 0 WHERE id = $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, ID)
+	return err
+}
+
+func (r *repository) Unlock(ctx context.Context, ID string) error {
+	sqlQuery := "UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = $1"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, ID)
+	return err
+}
+
+// UpdatePasswordHash swaps the hash only while it is still oldHash, so a rehash never undoes a password change made meanwhile
+func (r *repository) UpdatePasswordHash(ctx context.Context, ID string, oldHash string, newHash string) error {
+	sqlQuery := "UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3"
+
+	stmt, err := r.DB.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, newHash, ID, oldHash)
+	return err
 }
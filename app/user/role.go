@@ -0,0 +1,82 @@
+package user
+
+type Permission string
+
+const (
+	RoleUser      = "user"
+	RoleCreator   = "creator"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+const (
+	PermissionProfileRead      Permission = "profile:read"
+	PermissionProfileManage    Permission = "profile:manage"
+	PermissionCampaignsRead    Permission = "campaigns:read"
+	PermissionCampaignsCreate  Permission = "campaigns:create"
+	PermissionCampaignsUpload  Permission = "campaigns:upload"
+	PermissionCampaignsReview  Permission = "campaigns:review"
+	PermissionTransactionsRead Permission = "transactions:read"
+	PermissionUsersRead        Permission = "users:read"
+	PermissionUsersManage      Permission = "users:manage"
+	PermissionRolesAssign      Permission = "roles:assign"
+	PermissionAdminAccess      Permission = "admin:access"
+)
+
+// every role includes the permissions of the roles before it
+var rolePermissions = map[string][]Permission{
+	RoleUser: {
+		PermissionProfileRead,
+		PermissionProfileManage,
+		PermissionCampaignsRead,
+		PermissionTransactionsRead,
+	},
+	RoleCreator: {
+		PermissionProfileRead,
+		PermissionProfileManage,
+		PermissionCampaignsRead,
+		PermissionCampaignsCreate,
+		PermissionCampaignsUpload,
+		PermissionTransactionsRead,
+	},
+	RoleModerator: {
+		PermissionProfileRead,
+		PermissionProfileManage,
+		PermissionCampaignsRead,
+		PermissionCampaignsCreate,
+		PermissionCampaignsUpload,
+		PermissionTransactionsRead,
+		PermissionCampaignsReview,
+		PermissionUsersRead,
+	},
+	RoleAdmin: {
+		PermissionProfileRead,
+		PermissionProfileManage,
+		PermissionCampaignsRead,
+		PermissionCampaignsCreate,
+		PermissionCampaignsUpload,
+		PermissionTransactionsRead,
+		PermissionCampaignsReview,
+		PermissionUsersRead,
+		PermissionUsersManage,
+		PermissionRolesAssign,
+		PermissionAdminAccess,
+	},
+}
+
+// IsValidRole reports whether the role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether the user's role grants the permission, unknown roles grant nothing
+func (u User) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[u.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,82 +5,277 @@ import (
 	"errors"
 	"funding-app/app/helper"
 	"funding-app/app/key"
+	"funding-app/app/mailer"
+	"funding-app/app/media"
+	"funding-app/app/passwordhash"
+	"funding-app/app/passwordpolicy"
 	"mime/multipart"
+	"strings"
 	"sync"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	log "github.com/sirupsen/logrus"
 )
 
 type Service interface {
-	RegisterUser(input RegisterUserInput) (User, error)
-	LoginUser(input LoginUserInput) (User, error)
-	IsEmailAvailable(input CheckEmailInput) (bool, error)
-	UploadAvatar(userID string, uploadedFile multipart.File) (User, error)
-	GetUserByID(userID string) (User, error)
+	RegisterUser(ctx context.Context, input RegisterUserInput) (User, error)
+	RegisterExternalUser(ctx context.Context, input RegisterExternalUserInput) (User, error)
+	ClaimEmail(ctx context.Context, userID string) (User, error)
+	LoginUser(ctx context.Context, input LoginUserInput) (User, error)
+	RecordFailedLogin(ctx context.Context, userID string) error
+	ResetFailedLogins(ctx context.Context, user User) error
+	IsEmailAvailable(ctx context.Context, input CheckEmailInput) (bool, error)
+	UploadAvatar(ctx context.Context, userID string, uploadedFile multipart.File) (User, error)
+	GetUserByID(ctx context.Context, userID string) (User, error)
+	UpdateProfile(ctx context.Context, userID string, input UpdateProfileInput) (User, error)
+	ChangePassword(ctx context.Context, userID string, input ChangePasswordInput) (User, error)
+	VerifyPassword(ctx context.Context, userID string, password string) (User, error)
+	CheckNewPassword(ctx context.Context, userID string, password string) error
+	ResetPassword(ctx context.Context, userID string, password string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	VerifyEmail(ctx context.Context, userID string, email string) (User, error)
+	BecomeCreator(ctx context.Context, userID string) (User, error)
+	SearchUsers(ctx context.Context, input SearchUsersInput) ([]User, int, error)
+	SuspendUser(ctx context.Context, userID string, input SuspendUserInput) (User, error)
+	UnsuspendUser(ctx context.Context, userID string) (User, error)
+	ChangeRole(ctx context.Context, userID string, input ChangeRoleInput) (User, error)
+	UnlockUser(ctx context.Context, userID string) (User, error)
+	AnonymizeUser(ctx context.Context, userID string) (User, error)
 }
 
+var (
+	ErrEmailAlreadyUsed = errors.New("email is already used by another account")
+	ErrWrongPassword    = errors.New("current password is wrong")
+	ErrEmailChanged     = errors.New("email has changed since the verification link was sent")
+	ErrUserSuspended    = errors.New("account is suspended")
+	ErrInvalidRole      = errors.New("invalid role")
+
+	// ErrInvalidCredentials is the only error a failed login gets, so a lockout can't be told apart from a wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
 type service struct {
-	userRepository Repository
+	userRepository   Repository
+	mediaService     media.Service
+	mailer           mailer.Mailer
+	passwordHasher   passwordhash.Hasher
+	passwordPolicy   passwordpolicy.Policy
+	maxLoginFailures int
+	lockoutDuration  time.Duration
+
+	// dummyPasswordHash keeps logins for unknown emails as slow as logins with a wrong password
+	dummyPasswordHash string
 }
 
-func NewService(userRepository Repository) Service {
-	return &service{userRepository}
+func NewService(userRepository Repository, mediaService media.Service, mailer mailer.Mailer, passwordHasher passwordhash.Hasher, passwordPolicy passwordpolicy.Policy) Service {
+	dummyPasswordHash, err := passwordHasher.Hash("dummy-password")
+	if err != nil {
+		log.Error(err)
+	}
+
+	return &service{
+		userRepository:    userRepository,
+		mediaService:      mediaService,
+		mailer:            mailer,
+		passwordHasher:    passwordHasher,
+		passwordPolicy:    passwordPolicy,
+		maxLoginFailures:  helper.GetEnvInt("LOGIN_MAX_FAILURES", 5),
+		lockoutDuration:   helper.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		dummyPasswordHash: dummyPasswordHash,
+	}
 }
 
-func (s *service) RegisterUser(input RegisterUserInput) (User, error) {
+func (s *service) RegisterUser(ctx context.Context, input RegisterUserInput) (User, error) {
 	var user User
 	userID := helper.GenerateID()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	user.ID = userID
 	user.Name = input.Name
 	user.Occupation = input.Occupation
 	user.Email = input.Email
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.MinCost)
+	err := s.passwordPolicy.Check(input.Password, input.Name, input.Email)
 	if err != nil {
 		return user, err
 	}
 
-	password := string(passwordHash)
-	user.PasswordHash = password
-	user.Role = "user"
+	passwordHash, err := s.passwordHasher.Hash(input.Password)
+	if err != nil {
+		return user, err
+	}
+
+	user.PasswordHash = passwordHash
+	user.Role = RoleUser
 
 	newUser, err := s.userRepository.Save(ctx, user)
 	if err != nil {
 		return newUser, err
 	}
 
+	s.mailer.SendAsync(mailer.Mail{
+		To:       newUser.Email,
+		Locale:   input.Locale,
+		Template: mailer.TemplateWelcome,
+		Data: map[string]interface{}{
+			"Name": newUser.Name,
+		},
+	})
+
 	return newUser, nil
 }
 
-func (s *service) LoginUser(input LoginUserInput) (User, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// RegisterExternalUser creates an account without a password, the user can set one later with a password reset
+func (s *service) RegisterExternalUser(ctx context.Context, input RegisterExternalUserInput) (User, error) {
+	var user User
+
+	user.ID = helper.GenerateID()
+	user.Name = strings.TrimSpace(input.Name)
+	user.Email = input.Email
+	user.EmailVerified = true
+	user.Role = RoleUser
+
+	if user.Name == "" {
+		user.Name = strings.Split(input.Email, "@")[0]
+	}
+
+	newUser, err := s.userRepository.Save(ctx, user)
+	if err != nil {
+		return newUser, err
+	}
+
+	s.mailer.SendAsync(mailer.Mail{
+		To:       newUser.Email,
+		Locale:   input.Locale,
+		Template: mailer.TemplateWelcome,
+		Data: map[string]interface{}{
+			"Name": newUser.Name,
+		},
+	})
+
+	return newUser, nil
+}
+
+// ClaimEmail is called when the owner of the email proved it somewhere else, a password set by someone
+// who never verified the address is dropped so they can't keep access to the account
+func (s *service) ClaimEmail(ctx context.Context, userID string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.EmailVerified {
+		return user, nil
+	}
 
+	updatedUser, err := s.userRepository.ClaimEmail(ctx, user.ID)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+// LoginUser locks the account for a while after too many wrong passwords in a row, the password
+// alone doesn't reset the failures because a second factor may still fail
+func (s *service) LoginUser(ctx context.Context, input LoginUserInput) (User, error) {
 	user, err := s.userRepository.FindByEmail(ctx, input.Email)
 	if err != nil {
 		return user, err
 	}
 
 	if user.ID == "" {
-		return user, errors.New("no user found on that email")
+		s.passwordHasher.Compare(s.dummyPasswordHash, input.Password)
+		return User{}, ErrInvalidCredentials
+	}
+
+	// a locked account is rejected even with the right password, and accounts created through
+	// an identity provider have no password to log in with
+	if user.Locked || user.PasswordHash == "" {
+		s.passwordHasher.Compare(s.dummyPasswordHash, input.Password)
+		return User{}, ErrInvalidCredentials
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
+	err = s.passwordHasher.Compare(user.PasswordHash, input.Password)
 	if err != nil {
-		return user, err
+		s.recordFailedLogin(user)
+		return User{}, ErrInvalidCredentials
+	}
+
+	if user.Suspended {
+		return user, ErrUserSuspended
+	}
+
+	// the plain password is only around at login, so hashes made with an older cost or algorithm are upgraded here
+	if s.passwordHasher.NeedsRehash(user.PasswordHash) {
+		s.rehashPassword(ctx, user, input.Password)
+	}
+
+	return user, nil
+}
+
+// RecordFailedLogin counts a failed second factor against the account like a wrong password
+func (s *service) RecordFailedLogin(ctx context.Context, userID string) error {
+	user, err := s.userRepository.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == "" {
+		return nil
+	}
+
+	s.recordFailedLogin(user)
+	return nil
+}
+
+// ResetFailedLogins is called once the login passed every factor
+func (s *service) ResetFailedLogins(ctx context.Context, user User) error {
+	if user.FailedLogins == 0 {
+		return nil
+	}
+
+	return s.userRepository.ResetFailedLogins(ctx, user.ID)
+}
+
+func (s *service) recordFailedLogin(user User) {
+	// recorded outside the request so hanging up after a wrong guess doesn't skip the count
+	locked, err := s.userRepository.RecordFailedLogin(context.Background(), user.ID, s.maxLoginFailures, time.Now().Add(s.lockoutDuration))
+	if err != nil {
+		log.Error(err)
 	}
 
-	return user, err
+	if locked {
+		log.WithField("user_id", user.ID).Warn("account locked after too many failed logins")
+
+		s.mailer.SendAsync(mailer.Mail{
+			To:       user.Email,
+			Template: mailer.TemplateAccountLocked,
+			Data: map[string]interface{}{
+				"Name":      user.Name,
+				"LockedFor": s.lockoutDuration.String(),
+			},
+		})
+	}
 }
 
-func (s *service) IsEmailAvailable(input CheckEmailInput) (bool, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// rehashPassword doesn't fail the login, the old hash keeps working until the next attempt
+func (s *service) rehashPassword(ctx context.Context, user User, password string) {
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	err = s.userRepository.UpdatePasswordHash(ctx, user.ID, user.PasswordHash, passwordHash)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	log.WithField("user_id", user.ID).Info("password rehashed")
+}
 
+func (s *service) IsEmailAvailable(ctx context.Context, input CheckEmailInput) (bool, error) {
 	user, err := s.userRepository.FindByEmail(ctx, input.Email)
 	if err != nil {
 		return false, err
@@ -93,11 +288,8 @@ func (s *service) IsEmailAvailable(input CheckEmailInput) (bool, error) {
 	return false, nil
 }
 
-func (s *service) UploadAvatar(userID string, uploadedFile multipart.File) (User, error) {
+func (s *service) UploadAvatar(ctx context.Context, userID string, uploadedFile multipart.File) (User, error) {
 	var wg sync.WaitGroup
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	ch := make(chan key.FileUploadResponse)
 	defer close(ch)
 
@@ -109,7 +301,7 @@ func (s *service) UploadAvatar(userID string, uploadedFile multipart.File) (User
 	wg.Add(1)
 
 	// make goroutine with passing channel
-	go helper.ImageUploadAvatarHandler(&wg, uploadedFile, ch)
+	go media.ImageUploadAvatarHandler(ctx, &wg, s.mediaService, uploadedFile, ch)
 	fileResponse := <-ch
 
 	wg.Wait()
@@ -118,19 +310,24 @@ func (s *service) UploadAvatar(userID string, uploadedFile multipart.File) (User
 		return user, fileResponse.Err
 	}
 
-	user.AvatarFileName = fileResponse.SecureURL
-	updatedUser, err := s.userRepository.Update(ctx, user)
+	// only the avatar columns are written, a password change or suspension made while the image
+	// was processed must not be overwritten with the user read above
+	previousMediaID, err := s.userRepository.UpdateAvatar(ctx, user.ID, fileResponse.MediaID, fileResponse.SecureURL, fileResponse.Variants)
 	if err != nil {
-		return updatedUser, err
+		s.mediaService.Release(ctx, fileResponse.MediaID)
+		return user, err
 	}
 
-	return updatedUser, nil
-}
+	// the replaced avatar loses its reference
+	err = s.mediaService.Release(ctx, previousMediaID)
+	if err != nil {
+		log.Error(err)
+	}
 
-func (s *service) GetUserByID(userID string) (User, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	return s.GetUserByID(ctx, user.ID)
+}
 
+func (s *service) GetUserByID(ctx context.Context, userID string) (User, error) {
 	user, err := s.userRepository.FindByID(ctx, userID)
 	if err != nil {
 		return user, err
@@ -142,3 +339,273 @@ func (s *service) GetUserByID(userID string) (User, error) {
 
 	return user, nil
 }
+
+func (s *service) UpdateProfile(ctx context.Context, userID string, input UpdateProfileInput) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	if input.Name != nil {
+		user.Name = strings.TrimSpace(*input.Name)
+	}
+
+	if input.Occupation != nil {
+		user.Occupation = strings.TrimSpace(*input.Occupation)
+	}
+
+	if input.Email != nil && !strings.EqualFold(*input.Email, user.Email) {
+		existingUser, err := s.userRepository.FindByEmail(ctx, *input.Email)
+		if err != nil {
+			return user, err
+		}
+
+		if existingUser.ID != "" && existingUser.ID != user.ID {
+			return user, ErrEmailAlreadyUsed
+		}
+
+		// the new address has to be verified again
+		user.Email = *input.Email
+		user.EmailVerified = false
+	}
+
+	updatedUser, err := s.userRepository.UpdateProfile(ctx, user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+// ChangePassword bumps the token version so every token issued before the change stops working
+func (s *service) ChangePassword(ctx context.Context, userID string, input ChangePasswordInput) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	err = s.passwordHasher.Compare(user.PasswordHash, input.CurrentPassword)
+	if err != nil {
+		return user, ErrWrongPassword
+	}
+
+	err = s.passwordPolicy.Check(input.NewPassword, user.Name, user.Email)
+	if err != nil {
+		return user, err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(input.NewPassword)
+	if err != nil {
+		return user, err
+	}
+
+	updatedUser, err := s.userRepository.UpdatePassword(ctx, user.ID, passwordHash)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+// VerifyPassword reauthenticates a logged in user before a sensitive change
+func (s *service) VerifyPassword(ctx context.Context, userID string, password string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	err = s.passwordHasher.Compare(user.PasswordHash, password)
+	if err != nil {
+		return user, ErrWrongPassword
+	}
+
+	return user, nil
+}
+
+// CheckNewPassword runs the password policy against the account before a flow commits to a password change
+func (s *service) CheckNewPassword(ctx context.Context, userID string, password string) error {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.passwordPolicy.Check(password, user.Name, user.Email)
+}
+
+// ResetPassword sets a new password without the old one, callers must have verified the user another way
+func (s *service) ResetPassword(ctx context.Context, userID string, password string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	err = s.passwordPolicy.Check(password, user.Name, user.Email)
+	if err != nil {
+		return user, err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		return user, err
+	}
+
+	updatedUser, err := s.userRepository.UpdatePassword(ctx, user.ID, passwordHash)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	// proving access to the mailbox is enough to lift a lockout
+	err = s.userRepository.Unlock(ctx, user.ID)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	updatedUser.FailedLogins = 0
+	updatedUser.Locked = false
+
+	return updatedUser, nil
+}
+
+func (s *service) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	user, err := s.userRepository.FindByEmail(ctx, email)
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// VerifyEmail only verifies the address the link was issued for
+func (s *service) VerifyEmail(ctx context.Context, userID string, email string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	if !strings.EqualFold(user.Email, email) {
+		return user, ErrEmailChanged
+	}
+
+	if user.EmailVerified {
+		return user, nil
+	}
+
+	updatedUser, err := s.userRepository.MarkEmailVerified(ctx, user.ID, email)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+// BecomeCreator lets a user start creating campaigns, roles that already can keep their role
+func (s *service) BecomeCreator(ctx context.Context, userID string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.Can(PermissionCampaignsCreate) {
+		return user, nil
+	}
+
+	updatedUser, err := s.userRepository.UpdateRole(ctx, user.ID, RoleCreator)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+func (s *service) SearchUsers(ctx context.Context, input SearchUsersInput) ([]User, int, error) {
+	return s.userRepository.Search(ctx, input)
+}
+
+// SuspendUser also bumps the token version so the user is logged out everywhere
+func (s *service) SuspendUser(ctx context.Context, userID string, input SuspendUserInput) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	updatedUser, err := s.userRepository.Suspend(ctx, user.ID, strings.TrimSpace(input.Reason))
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+func (s *service) UnsuspendUser(ctx context.Context, userID string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	updatedUser, err := s.userRepository.Unsuspend(ctx, user.ID)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+// ChangeRole takes effect on the next request, permissions are checked against the stored role
+func (s *service) ChangeRole(ctx context.Context, userID string, input ChangeRoleInput) (User, error) {
+	if !IsValidRole(input.Role) {
+		return User{}, ErrInvalidRole
+	}
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	updatedUser, err := s.userRepository.UpdateRole(ctx, user.ID, input.Role)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+func (s *service) UnlockUser(ctx context.Context, userID string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	err = s.userRepository.Unlock(ctx, user.ID)
+	if err != nil {
+		return user, err
+	}
+
+	user.FailedLogins = 0
+	user.Locked = false
+
+	return user, nil
+}
+
+// AnonymizeUser removes the personal data of a deleted account, the row stays so financial records keep their owner
+func (s *service) AnonymizeUser(ctx context.Context, userID string) (User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+
+	user.Name = "Deleted user"
+	user.Email = "deleted-" + user.ID + "@deleted.invalid"
+	user.Role = RoleUser
+
+	avatarMediaID, err := s.userRepository.Anonymize(ctx, user)
+	if err != nil {
+		return user, err
+	}
+
+	err = s.mediaService.Release(ctx, avatarMediaID)
+	if err != nil {
+		log.Error(err)
+	}
+
+	return s.GetUserByID(ctx, user.ID)
+}
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN avatar_variants JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE campaign_images ADD COLUMN variants JSONB NOT NULL DEFAULT '{}';
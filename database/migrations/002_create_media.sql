@@ -0,0 +1,14 @@
+CREATE TABLE media (
+  id VARCHAR(32) PRIMARY KEY,
+  hash CHAR(64) NOT NULL,
+  kind VARCHAR(32) NOT NULL,
+  file_keys JSONB NOT NULL DEFAULT '{}',
+  variants JSONB NOT NULL DEFAULT '{}',
+  ref_count INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  UNIQUE (hash, kind)
+);
+
+ALTER TABLE users ADD COLUMN avatar_media_id VARCHAR(32) REFERENCES media (id) ON DELETE SET NULL;
+ALTER TABLE campaign_images ADD COLUMN media_id VARCHAR(32) REFERENCES media (id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+CREATE INDEX media_updated_at_idx ON media (updated_at);
+CREATE INDEX users_avatar_media_id_idx ON users (avatar_media_id);
+CREATE INDEX campaign_images_media_id_idx ON campaign_images (media_id);
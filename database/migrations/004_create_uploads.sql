@@ -0,0 +1,17 @@
+CREATE TABLE uploads (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  kind VARCHAR(32) NOT NULL,
+  target_id VARCHAR(32) NOT NULL DEFAULT '',
+  is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+  status VARCHAR(16) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  staged_path TEXT NOT NULL,
+  url TEXT NOT NULL DEFAULT '',
+  variants JSONB NOT NULL DEFAULT '{}',
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX uploads_status_idx ON uploads (status);
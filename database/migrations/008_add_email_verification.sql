@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- accounts created before verification existed keep working
+UPDATE users SET email_verified = TRUE;
+
+CREATE TABLE email_verifications (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  email VARCHAR(255) NOT NULL,
+  sent_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX email_verifications_user_id_sent_at_idx ON email_verifications (user_id, sent_at);
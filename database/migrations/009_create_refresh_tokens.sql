@@ -0,0 +1,14 @@
+CREATE TABLE refresh_tokens (
+  id VARCHAR(32) PRIMARY KEY,
+  family_id VARCHAR(32) NOT NULL,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  token_version INT NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  rotated_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
@@ -0,0 +1,19 @@
+CREATE TABLE sessions (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  device VARCHAR(255) NOT NULL DEFAULT '',
+  ip_address VARCHAR(64) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  last_seen_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_revoked_at_idx ON sessions (revoked_at);
+
+-- refresh token families issued before sessions existed become sessions of their own
+INSERT INTO sessions (id, user_id, last_seen_at, created_at)
+SELECT family_id, user_id, MAX(created_at), MIN(created_at) FROM refresh_tokens GROUP BY family_id, user_id;
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_family_id_fkey FOREIGN KEY (family_id) REFERENCES sessions (id) ON DELETE CASCADE;
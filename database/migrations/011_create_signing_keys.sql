@@ -0,0 +1,9 @@
+CREATE TABLE signing_keys (
+  id VARCHAR(32) PRIMARY KEY,
+  algorithm VARCHAR(16) NOT NULL,
+  private_key TEXT NOT NULL,
+  retired_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX signing_keys_algorithm_created_at_idx ON signing_keys (algorithm, created_at);
@@ -0,0 +1,8 @@
+UPDATE users SET role = 'user' WHERE role IS NULL OR role NOT IN ('user', 'creator', 'moderator', 'admin');
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'creator', 'moderator', 'admin'));
+
+-- only creators can start campaigns, keep that ability for users who already have some
+UPDATE users SET role = 'creator' WHERE role = 'user' AND id IN (SELECT user_id FROM campaigns);
@@ -0,0 +1,35 @@
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspend_reason TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE campaigns ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check CHECK (status IN ('active', 'closed', 'unpublished'));
+CREATE INDEX campaigns_status_idx ON campaigns (status);
+
+CREATE TABLE IF NOT EXISTS transactions (
+  id VARCHAR(32) PRIMARY KEY,
+  campaign_id VARCHAR(32) NOT NULL REFERENCES campaigns (id),
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id),
+  amount INT NOT NULL,
+  status VARCHAR(32) NOT NULL,
+  code VARCHAR(64) NOT NULL DEFAULT '',
+  payment_url TEXT,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transactions_user_id_created_at_idx ON transactions (user_id, created_at);
+
+CREATE TABLE audit_logs (
+  id VARCHAR(32) PRIMARY KEY,
+  actor_id VARCHAR(32) NOT NULL REFERENCES users (id),
+  action VARCHAR(64) NOT NULL,
+  target_type VARCHAR(32) NOT NULL,
+  target_id VARCHAR(32) NOT NULL,
+  metadata JSONB NOT NULL DEFAULT '{}',
+  ip_address VARCHAR(64) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX audit_logs_actor_id_idx ON audit_logs (actor_id);
+CREATE INDEX audit_logs_target_id_idx ON audit_logs (target_id);
+CREATE INDEX audit_logs_created_at_idx ON audit_logs (created_at);
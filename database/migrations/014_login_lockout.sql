@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN failed_logins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+
+CREATE TABLE login_failures (
+  id VARCHAR(32) PRIMARY KEY,
+  email VARCHAR(255) NOT NULL,
+  ip_address VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX login_failures_email_created_at_idx ON login_failures (email, created_at);
+CREATE INDEX login_failures_ip_address_created_at_idx ON login_failures (ip_address, created_at);
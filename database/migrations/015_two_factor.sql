@@ -0,0 +1,28 @@
+CREATE TABLE two_factors (
+  user_id VARCHAR(32) PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT false,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE recovery_codes (
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  code_hash CHAR(64) NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE two_factor_challenges (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  attempts INT NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX two_factor_challenges_user_id_idx ON two_factor_challenges (user_id);
+CREATE INDEX two_factor_challenges_expires_at_idx ON two_factor_challenges (expires_at);
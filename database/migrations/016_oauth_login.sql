@@ -0,0 +1,22 @@
+CREATE TABLE oauth_states (
+  id VARCHAR(32) PRIMARY KEY,
+  state_hash CHAR(64) NOT NULL UNIQUE,
+  provider VARCHAR(64) NOT NULL,
+  nonce VARCHAR(64) NOT NULL,
+  code_verifier VARCHAR(128) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX oauth_states_expires_at_idx ON oauth_states (expires_at);
+
+CREATE TABLE user_identities (
+  provider VARCHAR(64) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  email VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
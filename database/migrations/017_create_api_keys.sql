@@ -0,0 +1,16 @@
+CREATE TABLE api_keys (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash CHAR(64) NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  expires_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP,
+  last_used_ip VARCHAR(64) NOT NULL DEFAULT '',
+  usage_count BIGINT NOT NULL DEFAULT 0,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
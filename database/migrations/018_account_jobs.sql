@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE TABLE account_jobs (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(32) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  kind VARCHAR(16) NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  archive_path TEXT NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX account_jobs_user_id_idx ON account_jobs (user_id);
+CREATE INDEX account_jobs_status_idx ON account_jobs (status);
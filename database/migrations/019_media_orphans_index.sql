@@ -0,0 +1 @@
+CREATE INDEX media_orphans_idx ON media (id) WHERE ref_count = 0;
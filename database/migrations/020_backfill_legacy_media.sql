@@ -0,0 +1,48 @@
+-- images stored before media existed get a media row so replacing them releases their files,
+-- keys come from the url (cloudinary public id, or the key under the default /media/ base url)
+-- and the hash covers the url because the content was never hashed
+WITH legacy AS (
+  SELECT 'avatar' AS kind, avatar_file_name AS url, avatar_variants AS variants
+  FROM users
+  WHERE avatar_media_id IS NULL AND COALESCE(avatar_file_name, '') <> ''
+  UNION ALL
+  SELECT 'campaign_image', file_name, variants
+  FROM campaign_images
+  WHERE media_id IS NULL AND COALESCE(file_name, '') <> ''
+), grouped AS (
+  SELECT kind, url, (array_agg(variants))[1] AS variants, COUNT(*) AS refs
+  FROM legacy
+  GROUP BY kind, url
+), normalized AS (
+  SELECT kind, url, refs,
+    CASE WHEN variants = '{}' THEN jsonb_build_object('original', url) ELSE variants END AS variants
+  FROM grouped
+)
+INSERT INTO media (id, hash, kind, file_keys, variants, ref_count, created_at, updated_at)
+SELECT
+  md5(n.kind || n.url),
+  encode(sha256(convert_to(n.kind || n.url, 'UTF8')), 'hex'),
+  n.kind,
+  COALESCE((
+    SELECT jsonb_object_agg(k.name, k.file_key) FILTER (WHERE k.file_key IS NOT NULL)
+    FROM (
+      SELECT v.key AS name,
+        CASE WHEN v.value ~ '^https?://res\.cloudinary\.com/'
+          THEN regexp_replace(substring(v.value from '/upload/(?:v[0-9]+/)?(.*)$'), '\.[^./]*$', '')
+          ELSE substring(v.value from '/media/(.*)$')
+        END AS file_key
+      FROM jsonb_each_text(n.variants) v
+    ) k
+  ), '{}'),
+  n.variants,
+  n.refs,
+  now()::timestamp,
+  now()::timestamp
+FROM normalized n
+ON CONFLICT DO NOTHING;
+
+UPDATE users SET avatar_media_id = md5('avatar' || avatar_file_name)
+WHERE avatar_media_id IS NULL AND COALESCE(avatar_file_name, '') <> '';
+
+UPDATE campaign_images SET media_id = md5('campaign_image' || file_name)
+WHERE media_id IS NULL AND COALESCE(file_name, '') <> '';
@@ -2,10 +2,17 @@ module funding-app
 
 go 1.17
 
-require golang.org/x/crypto v0.0.0-20220411220226-7b82a4e95df4
+require (
+	github.com/chai2010/webp v1.4.0
+	github.com/cloudinary/cloudinary-go v1.7.0
+	github.com/golang-jwt/jwt/v4 v4.5.0
+	github.com/nbutton23/zxcvbn-go v0.0.0-20210217022336-fa2cb2858354
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	golang.org/x/crypto v0.0.0-20220411220226-7b82a4e95df4
+	golang.org/x/image v0.0.0-20220413100746-70e8d0d3baa9
+)
 
 require (
-	github.com/cloudinary/cloudinary-go v1.7.0 // indirect
 	github.com/creasty/defaults v1.5.1 // indirect
 	github.com/go-playground/locales v0.14.0 // indirect
 	github.com/go-playground/universal-translator v0.18.0 // indirect
@@ -16,15 +23,14 @@ require (
 
 require (
 	github.com/Masterminds/squirrel v1.5.2 // indirect
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
-	github.com/go-chi/chi/v5 v5.0.7 // indirect
+	github.com/go-chi/chi/v5 v5.0.7
 	github.com/go-chi/cors v1.2.1
 	github.com/go-playground/validator/v10 v10.10.1
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/joho/godotenv v1.4.0 // indirect
+	github.com/google/uuid v1.3.0
+	github.com/joho/godotenv v1.4.0
 	github.com/lann/builder v0.0.0-20180802200727-47ae307949d0 // indirect
 	github.com/lann/ps v0.0.0-20150810152359-62de8c46ede0 // indirect
-	github.com/lib/pq v1.10.5 // indirect
-	github.com/sirupsen/logrus v1.8.1 // indirect
+	github.com/lib/pq v1.10.5
+	github.com/sirupsen/logrus v1.8.1
 	golang.org/x/sys v0.0.0-20210806184541-e5e7981a1069 // indirect
 )
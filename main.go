@@ -1,15 +1,36 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"funding-app/app/account"
+	"funding-app/app/apikey"
+	"funding-app/app/audit"
 	"funding-app/app/auth"
 	"funding-app/app/campaign"
+	"funding-app/app/emailverification"
 	"funding-app/app/handler"
+	"funding-app/app/helper"
+	"funding-app/app/imaging"
+	"funding-app/app/loginattempt"
+	"funding-app/app/mailer"
+	"funding-app/app/media"
 	cm "funding-app/app/middleware"
+	"funding-app/app/oauth"
+	"funding-app/app/passwordhash"
+	"funding-app/app/passwordpolicy"
+	"funding-app/app/passwordreset"
+	"funding-app/app/storage"
+	"funding-app/app/transaction"
+	"funding-app/app/twofactor"
+	"funding-app/app/upload"
 	"funding-app/app/user"
 	"funding-app/database"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -42,35 +63,185 @@ func main() {
 	log.Println(status)
 	fmt.Println("postgreSQL connected!")
 
+	// storage
+	mediaStorage, err := storage.NewStorage()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imageProcessor, err := imaging.NewProcessor()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mailDriver, err := mailer.NewDriver()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	appMailer, err := mailer.NewMailer(mailDriver, mailer.Config{
+		AppName:       helper.GetEnv("APP_NAME", "Funding App"),
+		AppURL:        helper.GetEnv("APP_URL", "http://localhost:3000"),
+		DefaultLocale: helper.GetEnv("MAIL_DEFAULT_LOCALE", "en"),
+		Workers:       helper.GetEnvInt("MAIL_WORKERS", 2),
+		QueueSize:     helper.GetEnvInt("MAIL_QUEUE_SIZE", 100),
+		MaxAttempts:   helper.GetEnvInt("MAIL_MAX_ATTEMPTS", 3),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	passwordHasher, err := passwordhash.NewHasher()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	passwordPolicy, err := passwordpolicy.NewPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// repository
 	userRepository := user.NewUserRepository(db)
 	campaignRepository := campaign.NewCampaignRepository(db)
+	mediaRepository := media.NewMediaRepository(db)
+	passwordResetRepository := passwordreset.NewPasswordResetRepository(db)
+	emailVerificationRepository := emailverification.NewEmailVerificationRepository(db)
+	refreshTokenRepository := auth.NewRefreshTokenRepository(db)
+	transactionRepository := transaction.NewTransactionRepository(db)
+	auditRepository := audit.NewAuditRepository(db)
+	loginAttemptRepository := loginattempt.NewLoginAttemptRepository(db)
+	twoFactorRepository := twofactor.NewTwoFactorRepository(db)
+	oauthRepository := oauth.NewOAuthRepository(db)
+	apiKeyRepository := apikey.NewAPIKeyRepository(db)
+	accountRepository := account.NewAccountRepository(db)
 
 	// service
-	userService := user.NewService(userRepository)
-	authService := auth.NewJwtService()
-	campaignService := campaign.NewCampaignService(campaignRepository)
+	mediaService := media.NewMediaService(mediaRepository, mediaStorage, imageProcessor)
+	userService := user.NewService(userRepository, mediaService, appMailer, passwordHasher, passwordPolicy)
+	authService, err := auth.NewJwtService(refreshTokenRepository, userService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	campaignService := campaign.NewCampaignService(campaignRepository, mediaService)
+	transactionService := transaction.NewTransactionService(transactionRepository)
+	auditService := audit.NewAuditService(auditRepository)
+	loginAttemptService := loginattempt.NewLoginAttemptService(loginAttemptRepository)
+
+	passwordResetService := passwordreset.NewPasswordResetService(passwordResetRepository, userService, appMailer)
+	emailVerificationService, err := emailverification.NewEmailVerificationService(emailVerificationRepository, userService, appMailer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	twoFactorService, err := twofactor.NewTwoFactorService(twoFactorRepository, userService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	oauthService := oauth.NewOAuthService(oauthRepository, userService)
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepository)
+
+	uploadRepository := upload.NewUploadRepository(db)
+	uploadService, err := upload.NewUploadService(uploadRepository,
+		handler.NewUploadProcessors(userService, campaignService),
+		upload.Config{
+			Workers:     helper.GetEnvInt("UPLOAD_WORKERS", 4),
+			QueueSize:   helper.GetEnvInt("UPLOAD_QUEUE_SIZE", 100),
+			MaxAttempts: helper.GetEnvInt("UPLOAD_MAX_ATTEMPTS", 3),
+			Timeout:     helper.GetEnvDuration("UPLOAD_TIMEOUT", time.Minute),
+			StagingDir:  helper.GetEnv("UPLOAD_STAGING_DIR", filepath.Join(os.TempDir(), "funding-app-uploads")),
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountService, err := account.NewAccountService(accountRepository, userService, campaignService, transactionService, authService, appMailer,
+		account.Config{
+			Workers:         helper.GetEnvInt("ACCOUNT_JOB_WORKERS", 2),
+			QueueSize:       helper.GetEnvInt("ACCOUNT_JOB_QUEUE_SIZE", 100),
+			MaxAttempts:     helper.GetEnvInt("ACCOUNT_JOB_MAX_ATTEMPTS", 3),
+			ExportDir:       helper.GetEnv("ACCOUNT_EXPORT_DIR", filepath.Join(os.TempDir(), "funding-app-exports")),
+			ExportTTL:       helper.GetEnvDuration("ACCOUNT_EXPORT_TTL", 7*24*time.Hour),
+			ExportURL:       helper.GetEnv("ACCOUNT_EXPORT_URL", "http://localhost:3000/account/export"),
+			CleanupInterval: helper.GetEnvDuration("ACCOUNT_EXPORT_CLEANUP_INTERVAL", time.Hour),
+			DeletionURL:     helper.GetEnv("ACCOUNT_DELETION_URL", "http://localhost:3000/account/delete"),
+			DeletionTTL:     helper.GetEnvDuration("ACCOUNT_DELETION_TTL", time.Hour),
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go media.StartGarbageCollector(ctx, mediaService,
+		helper.GetEnvDuration("MEDIA_GC_INTERVAL", time.Hour),
+		helper.GetEnvDuration("MEDIA_GC_GRACE_PERIOD", 24*time.Hour),
+		helper.GetEnvBool("MEDIA_GC_DRY_RUN", false),
+	)
+
+	uploadService.Start(ctx)
+	appMailer.Start(ctx)
+	passwordResetService.Start(ctx)
+	authService.Start(ctx)
+	loginAttemptService.Start(ctx)
+	twoFactorService.Start(ctx)
+	oauthService.Start(ctx)
+	apiKeyService.Start(ctx)
+	accountService.Start(ctx)
 
 	// handler
-	userHandler := handler.NewUserHandler(userService, authService)
-	campaignHandler := handler.NewCampaignHandler(campaignService)
+	userHandler := handler.NewUserHandler(userService, authService, uploadService, emailVerificationService, loginAttemptService, twoFactorService)
+	campaignHandler := handler.NewCampaignHandler(campaignService, uploadService)
+	uploadHandler := handler.NewUploadHandler(uploadService)
+	passwordResetHandler := handler.NewPasswordResetHandler(passwordResetService)
+	emailVerificationHandler := handler.NewEmailVerificationHandler(emailVerificationService)
+	sessionHandler := handler.NewSessionHandler(authService)
+	jwksHandler := handler.NewJWKSHandler(authService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService, authService, userService, loginAttemptService)
+	oauthHandler := handler.NewOAuthHandler(oauthService, authService, twoFactorService, userService, loginAttemptService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	transactionHandler := handler.NewTransactionHandler(transactionService)
+	adminHandler := handler.NewAdminHandler(userService, campaignService, transactionService, auditService)
+
+	// forwarding headers are only believed when they come from one of these
+	trustedProxies, err := cm.ParseTrustedProxies(helper.GetEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// initial route
 	r := chi.NewRouter()
+	r.Use(cm.RealIP(trustedProxies))
 	r.Use(middleware.Logger)
 
 	r.Use(cors.Handler(cors.Options{
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
+	// serve uploaded files when they are stored on local disk
+	if localStorage, ok := mediaStorage.(*storage.LocalStorage); ok {
+		mediaHandler := handler.NewMediaHandler(localStorage)
+		r.Get("/media/*", mediaHandler.ServeMedia)
+		r.Head("/media/*", mediaHandler.ServeMedia)
+	}
+
 	// list of route
+	r.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
 			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -81,15 +252,108 @@ func main() {
 		r.Group(func(r chi.Router) {
 			r.Post("/users", userHandler.RegisterUser)
 			r.Post("/sessions", userHandler.LoginUser)
+			r.Post("/sessions/2fa", twoFactorHandler.VerifyChallenge)
+			r.Get("/oauth/providers", oauthHandler.GetProviders)
+			r.Get("/oauth/{provider}/authorize", oauthHandler.Authorize)
+			r.Post("/oauth/{provider}/callback", oauthHandler.Callback)
 			r.Post("/email_checkers", userHandler.IsEmailAvailable)
+			r.Post("/password-resets", passwordResetHandler.RequestPasswordReset)
+			r.Post("/password-resets/{token}", passwordResetHandler.ResetPassword)
+			r.Post("/email-verifications/{token}", emailVerificationHandler.VerifyEmail)
 
 			r.With(func(h http.Handler) http.Handler {
-				return cm.AuthMiddleware(h, authService, userService)
-			}).Post("/avatars", userHandler.UploadAvatar)
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/email-verifications", emailVerificationHandler.ResendVerification)
 
 			r.With(func(h http.Handler) http.Handler {
-				return cm.AuthMiddleware(h, authService, userService)
-			}).Post("/refresh-token", userHandler.RefreshToken)
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/avatars", userHandler.UploadAvatar)
+
+			r.Post("/refresh-token", userHandler.RefreshToken)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileRead)).Get("/users/me", userHandler.GetProfile)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Patch("/users/me", userHandler.UpdateProfile)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/password", userHandler.ChangePassword)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequireVerifiedEmail, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/creator", userHandler.BecomeCreator)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/sessions/logout", sessionHandler.Logout)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Get("/users/me/sessions", sessionHandler.GetSessions)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Delete("/users/me/sessions/{id}", sessionHandler.RevokeSession)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Get("/users/me/2fa", twoFactorHandler.GetStatus)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/2fa", twoFactorHandler.Enroll)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/2fa/confirm", twoFactorHandler.Confirm)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Delete("/users/me/2fa", twoFactorHandler.Disable)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Get("/users/me/api-keys", apiKeyHandler.GetAPIKeys)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/api-keys", apiKeyHandler.CreateAPIKey)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Delete("/users/me/api-keys/{id}", apiKeyHandler.RevokeAPIKey)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/exports", accountHandler.RequestExport)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Get("/users/me/exports/{id}", accountHandler.GetExport)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Get("/users/me/exports/{id}/download", accountHandler.DownloadExport)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/deletion/link", accountHandler.RequestDeletionLink)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionProfileManage)).Post("/users/me/deletion", accountHandler.DeleteAccount)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionCampaignsRead)).Get("/users/me/campaigns", campaignHandler.GetUserCampaigns)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionTransactionsRead)).Get("/users/me/transactions", transactionHandler.GetUserTransactions)
 		})
 
 		r.Group(func(r chi.Router) {
@@ -97,12 +361,48 @@ func main() {
 			r.Get("/campaigns/{id}", campaignHandler.GetCampaignDetail)
 
 			r.With(func(h http.Handler) http.Handler {
-				return cm.AuthMiddleware(h, authService, userService)
-			}).Post("/campaigns", campaignHandler.CreateCampaign)
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequireVerifiedEmail, cm.RequirePermission(user.PermissionCampaignsCreate)).Post("/campaigns", campaignHandler.CreateCampaign)
+
+			r.With(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequireVerifiedEmail, cm.RequirePermission(user.PermissionCampaignsUpload)).Post("/campaign-images", campaignHandler.UploadCampaignImage)
 
 			r.With(func(h http.Handler) http.Handler {
-				return cm.AuthMiddleware(h, authService, userService)
-			}).Post("/campaign-images", campaignHandler.UploadCampaignImage)
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequireVerifiedEmail, cm.RequirePermission(user.PermissionCampaignsUpload)).Delete("/campaign-images/{id}", campaignHandler.DeleteCampaignImage)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			})
+			// avatars go through the same uploads, every role can follow its own
+			r.Use(cm.RequirePermission(user.PermissionProfileRead))
+
+			r.Get("/uploads/{id}", uploadHandler.GetUpload)
+			r.Get("/uploads/{id}/events", uploadHandler.UploadEvents)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(func(h http.Handler) http.Handler {
+				return cm.AuthMiddleware(h, authService, userService, apiKeyService)
+			}, cm.RequirePermission(user.PermissionAdminAccess))
+
+			r.With(cm.RequirePermission(user.PermissionUsersRead)).Get("/users", adminHandler.SearchUsers)
+			r.With(cm.RequirePermission(user.PermissionUsersRead)).Get("/users/{id}", adminHandler.GetUser)
+			r.With(cm.RequirePermission(user.PermissionUsersRead)).Get("/users/{id}/transactions", adminHandler.GetUserTransactions)
+			r.With(cm.RequirePermission(user.PermissionUsersManage)).Post("/users/{id}/suspend", adminHandler.SuspendUser)
+			r.With(cm.RequirePermission(user.PermissionUsersManage)).Post("/users/{id}/unsuspend", adminHandler.UnsuspendUser)
+			r.With(cm.RequirePermission(user.PermissionUsersManage)).Post("/users/{id}/unlock", adminHandler.UnlockUser)
+			r.With(cm.RequirePermission(user.PermissionRolesAssign)).Patch("/users/{id}/role", adminHandler.ChangeRole)
+
+			r.With(cm.RequirePermission(user.PermissionCampaignsReview)).Get("/campaigns", adminHandler.SearchCampaigns)
+			r.With(cm.RequirePermission(user.PermissionCampaignsReview)).Post("/campaigns/{id}/close", adminHandler.CloseCampaign)
+			r.With(cm.RequirePermission(user.PermissionCampaignsReview)).Post("/campaigns/{id}/unpublish", adminHandler.UnpublishCampaign)
+			r.With(cm.RequirePermission(user.PermissionCampaignsReview)).Post("/campaigns/{id}/publish", adminHandler.PublishCampaign)
+
+			r.Get("/audit-logs", adminHandler.GetAuditLogs)
 		})
 	})
 